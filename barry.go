@@ -0,0 +1,121 @@
+package barry
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/go-barry/barry/core"
+)
+
+type Options struct {
+	FS       fs.FS
+	Config   *core.Config
+	Env      string
+	Logger   io.Writer
+	Services map[string]interface{}
+}
+
+type Server struct {
+	handler     http.Handler
+	router      http.Handler
+	reloader    core.LiveReloaderInterface
+	publicRules *core.PublicRules
+}
+
+func New(opts Options) (*Server, error) {
+	configFile := ""
+	if opts.Config == nil {
+		configFile = "barry.config.yml"
+	}
+	return newServer(opts, configFile)
+}
+
+func newServer(opts Options, configFile string) (*Server, error) {
+	env := opts.Env
+	if env == "" {
+		env = "prod"
+	}
+	if env != "dev" && env != "prod" {
+		return nil, fmt.Errorf("unknown env %q, expected dev or prod", opts.Env)
+	}
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = os.Stdout
+	}
+
+	var config core.Config
+	if opts.Config != nil {
+		config = *opts.Config
+	} else {
+		config = *core.LoadConfigFS(opts.FS, configFile)
+	}
+	core.ValidatePlugins(logger)
+
+	s := &Server{}
+	mux := http.NewServeMux()
+	publicDir := "public"
+	cacheStaticDir := filepath.Join(config.OutputDir, "static")
+
+	if env == "dev" {
+		setupDevStaticRoutes(mux, opts.FS, publicDir)
+	} else {
+		mux.HandleFunc("/static/", makeStaticHandler(opts.FS, publicDir, cacheStaticDir, logger))
+		mux.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
+			serveSiteFileWithHeaders(w, r, opts.FS, filepath.Join(publicDir, "favicon.ico"), "public, max-age=31536000, immutable")
+		})
+		mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+			serveSiteFileWithHeaders(w, r, opts.FS, filepath.Join(publicDir, "robots.txt"), "public, max-age=31536000, immutable")
+		})
+	}
+
+	ctx := core.RuntimeContext{
+		Env:         env,
+		EnableWatch: env == "dev",
+		Services:    opts.Services,
+		FS:          opts.FS,
+		ConfigFile:  configFile,
+		Logger:      logger,
+	}
+	if env == "dev" {
+		s.reloader = core.NewLiveReloader()
+		ctx.OnReload = s.reloader.BroadcastReload
+		mux.HandleFunc("/__barry_reload", s.reloader.Handler)
+	}
+
+	s.router = core.NewRouter(config, ctx)
+	mux.Handle("/", s.router)
+
+	s.publicRules = core.NewPublicRules(opts.FS, publicDir, env, logger)
+	if config.BasePath != "" {
+		fmt.Fprintln(logger, "📍 Mounted under", config.BasePath)
+	}
+
+	s.handler = core.MountBasePath(config.BasePath, s.publicRules.Wrap(mux))
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+func (s *Server) Reload() {
+	if router, ok := s.router.(interface{ Reload() }); ok {
+		router.Reload()
+	}
+	s.publicRules.Reload()
+	if s.reloader != nil {
+		s.reloader.BroadcastReload()
+	}
+}
+
+func (s *Server) Close() error {
+	if router, ok := s.router.(io.Closer); ok {
+		return router.Close()
+	}
+	return nil
+}
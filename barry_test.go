@@ -0,0 +1,170 @@
+package barry
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-barry/barry/core"
+)
+
+var realNewRouter = core.NewRouter
+var realLoadConfig = core.LoadConfig
+
+func newTestServer(t *testing.T, opts Options) *Server {
+	t.Helper()
+	wd, _ := os.Getwd()
+	_ = os.Chdir(t.TempDir())
+
+	core.NewRouter = realNewRouter
+	core.LoadConfig = realLoadConfig
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	srv, err := New(opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = srv.Close() })
+	return srv
+}
+
+func testSite() fstest.MapFS {
+	return fstest.MapFS{
+		"barry.config.yml":             {Data: []byte("outputDir: cache\n")},
+		"routes/index.html":            {Data: []byte("<!-- layout: components/layouts/base.html -->\n{{ define \"content\" }}home{{ end }}")},
+		"components/layouts/base.html": {Data: []byte(`{{ define "layout" }}<main>{{ template "content" . }}</main>{{ end }}`)},
+		"public/app.css":               {Data: []byte("body{}")},
+	}
+}
+
+func get(h http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestNew_ServesSiteFromFSUnderAnyMux(t *testing.T) {
+	var logs bytes.Buffer
+	srv := newTestServer(t, Options{FS: testSite(), Logger: &logs})
+
+	mux := http.NewServeMux()
+	mux.Handle("/site/", http.StripPrefix("/site", srv))
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+
+	if body := get(mux, "/site/").Body.String(); body != "<main>home</main>" {
+		t.Errorf("unexpected page %q", body)
+	}
+	if body := get(mux, "/site/static/app.css").Body.String(); body != "body{}" {
+		t.Errorf("unexpected asset %q", body)
+	}
+	if body := get(mux, "/health").Body.String(); body != "ok" {
+		t.Errorf("unexpected health %q", body)
+	}
+
+	get(mux, "/site/static/missing.css")
+	if !strings.Contains(logs.String(), "🛑 Static asset not found: /static/missing.css") {
+		t.Errorf("expected logs to go to the logger, got %q", logs.String())
+	}
+}
+
+func TestNew_ConfigOverridesConfigFile(t *testing.T) {
+	site := testSite()
+	site["routes/about/index.html"] = &fstest.MapFile{Data: []byte("<!-- layout: components/layouts/base.html -->\n{{ define \"content\" }}about{{ end }}")}
+
+	var logs bytes.Buffer
+	srv := newTestServer(t, Options{
+		FS:     site,
+		Logger: &logs,
+		Config: &core.Config{OutputDir: "cache", Redirects: []core.RedirectRule{{Source: "/old", Destination: "/about"}}},
+	})
+
+	rec := get(srv, "/old")
+	if rec.Code != http.StatusPermanentRedirect || rec.Header().Get("Location") != "/about" {
+		t.Errorf("expected redirect from config, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestNew_ReloadsConfigFileOnlyWhenReadFromIt(t *testing.T) {
+	defer func() { core.NewRouter = realNewRouter }()
+
+	var configFile string
+	core.NewRouter = func(c core.Config, ctx core.RuntimeContext) http.Handler {
+		configFile = ctx.ConfigFile
+		return http.NotFoundHandler()
+	}
+
+	if _, err := New(Options{FS: testSite(), Logger: &bytes.Buffer{}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if configFile != "barry.config.yml" {
+		t.Errorf("expected config file to be reloaded, got %q", configFile)
+	}
+
+	if _, err := New(Options{FS: testSite(), Logger: &bytes.Buffer{}, Config: &core.Config{}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if configFile != "" {
+		t.Errorf("expected passed config to be kept on reload, got %q", configFile)
+	}
+}
+
+func TestServer_ReloadPicksUpNewRoutes(t *testing.T) {
+	site := testSite()
+	srv := newTestServer(t, Options{FS: site, Logger: &bytes.Buffer{}})
+
+	if rec := get(srv, "/new"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 before reload, got %d", rec.Code)
+	}
+
+	site["routes/new/index.html"] = &fstest.MapFile{Data: []byte("<!-- layout: components/layouts/base.html -->\n{{ define \"content\" }}new{{ end }}")}
+	srv.Reload()
+
+	if body := get(srv, "/new").Body.String(); body != "<main>new</main>" {
+		t.Errorf("expected new route after reload, got %q", body)
+	}
+}
+
+func TestServer_CloseIsIdempotent(t *testing.T) {
+	srv := newTestServer(t, Options{FS: testSite(), Env: "dev", Logger: &bytes.Buffer{}})
+
+	if err := srv.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := srv.Close(); err != nil {
+		t.Errorf("unexpected error on second close: %v", err)
+	}
+}
+
+func TestNew_ServersKeepTheirOwnFS(t *testing.T) {
+	other := testSite()
+	other["routes/index.html"] = &fstest.MapFile{Data: []byte("<!-- layout: components/layouts/base.html -->\n{{ define \"content\" }}other{{ end }}")}
+
+	first := newTestServer(t, Options{FS: testSite(), Env: "dev", Logger: &bytes.Buffer{}})
+	second := newTestServer(t, Options{FS: other, Logger: &bytes.Buffer{}})
+	third := newTestServer(t, Options{Logger: &bytes.Buffer{}})
+
+	if body := get(first, "/").Body.String(); body != "<main>home</main>" {
+		t.Errorf("expected first site, got %q", body)
+	}
+	if body := get(second, "/").Body.String(); body != "<main>other</main>" {
+		t.Errorf("expected second site, got %q", body)
+	}
+	if rec := get(third, "/"); rec.Code == http.StatusOK {
+		t.Errorf("expected server without FS to read the empty working directory, got %q", rec.Body.String())
+	}
+
+	_ = first.Close()
+	if body := get(second, "/").Body.String(); body != "<main>other</main>" {
+		t.Errorf("expected second site to keep serving after first closed, got %q", body)
+	}
+}
+
+func TestNew_RejectsUnknownEnv(t *testing.T) {
+	if _, err := New(Options{Env: "staging", FS: testSite()}); err == nil || !strings.Contains(err.Error(), "unknown env") {
+		t.Errorf("expected unknown env error, got %v", err)
+	}
+}
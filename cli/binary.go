@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-barry/barry/core"
+)
+
+const binaryTmpDir = "_barry-binary"
+
+var binarySiteDirs = []string{"routes", "api", "components", "public"}
+
+const binaryMainSource = `// Code generated by barry build --binary. DO NOT EDIT.
+
+package main
+
+import (
+	"embed"
+	"flag"
+	"io/fs"
+	"log"
+
+	"github.com/go-barry/barry"
+)
+
+//go:embed all:site
+var embedded embed.FS
+
+func main() {
+	port := flag.Int("port", 8080, "port to listen on")
+	flag.Parse()
+
+	site, err := fs.Sub(embedded, "site")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	barry.Start(barry.RuntimeConfig{
+		Env:         "prod",
+		EnableCache: true,
+		Port:        *port,
+	}, barry.WithFS(site))
+}
+`
+
+func buildBinary(modName, out string) error {
+	if out == "" {
+		out = filepath.Base(modName)
+	}
+
+	if err := os.RemoveAll(binaryTmpDir); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", binaryTmpDir, err)
+	}
+	defer os.RemoveAll(binaryTmpDir)
+
+	config := core.LoadConfig("barry.config.yml")
+	entries, err := collectRegistryEntries(modName, core.LoadRouteTable(*config), filepath.Join(binaryTmpDir, generatedMiddlewareDir))
+	if err != nil {
+		return err
+	}
+	registry, err := registrySource("main", entries)
+	if err != nil {
+		return err
+	}
+
+	if err := osMkdirAllFunc(binaryTmpDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create %s: %w", binaryTmpDir, err)
+	}
+	mainFile := filepath.Join(binaryTmpDir, "main.go")
+	handlersFile := filepath.Join(binaryTmpDir, "barry_handlers.go")
+	if err := osWriteFileFunc(mainFile, []byte(binaryMainSource), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", mainFile, err)
+	}
+	if err := osWriteFileFunc(handlersFile, registry, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", handlersFile, err)
+	}
+
+	files := 0
+	siteDir := filepath.Join(binaryTmpDir, "site")
+	for _, root := range append(binarySiteDirs, "barry.config.yml") {
+		n, err := copySiteFiles(root, siteDir)
+		if err != nil {
+			return err
+		}
+		files += n
+	}
+
+	fmt.Printf("📦 Embedding %d site files and %d server files\n", files, len(entries))
+
+	cmd := buildExecCommand("go", "build", "-o", out, mainFile, handlersFile)
+	cmd.Dir = "."
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to build binary: %w", err)
+	}
+
+	fmt.Printf("✅ Built %s\n", out)
+	return nil
+}
+
+func copySiteFiles(root, dest string) (int, error) {
+	copied := 0
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if filepath.Ext(path) == ".so" || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, path)
+		if err := osMkdirAllFunc(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+		if err := osWriteFileFunc(target, data, 0644); err != nil {
+			return err
+		}
+		copied++
+		return nil
+	})
+	if err != nil {
+		return copied, fmt.Errorf("failed to copy %s: %w", root, err)
+	}
+	return copied, nil
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildCommand_BinaryEmbedsSiteAndRegistry(t *testing.T) {
+	tmp := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	_ = os.Chdir(tmp)
+
+	files := map[string]string{
+		"go.mod":                            "module example.com/site\n",
+		"barry.config.yml":                  "outputDir: cache\n",
+		"routes/blog/_slug/index.html":      `{{ define "content" }}{{ end }}`,
+		"routes/blog/_slug/index.server.go": "package slug\n\nfunc HandleRequest() {}\n",
+		"routes/blog/_slug/index.server.so": "stale plugin",
+		"routes/_middleware.server.go":      "package routes\n\nfunc Middleware() {}\n",
+		"api/ping/get.go":                   "package ping\n\nfunc HandleGet() {}\n",
+		"api/ping/get_test.go":              "package ping\n",
+		"public/css/app.css":                "body{}",
+	}
+	for path, content := range files {
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		_ = os.WriteFile(path, []byte(content), 0644)
+	}
+
+	originalExec := buildExecCommand
+	defer func() { buildExecCommand = originalExec }()
+
+	var args []string
+	var mainSrc, handlersSrc string
+	var middlewareCopied bool
+	embedded := map[string]bool{}
+	buildExecCommand = func(name string, a ...string) *exec.Cmd {
+		args = a
+		data, _ := os.ReadFile(filepath.Join(binaryTmpDir, "main.go"))
+		mainSrc = string(data)
+		data, _ = os.ReadFile(filepath.Join(binaryTmpDir, "barry_handlers.go"))
+		handlersSrc = string(data)
+		_, err := os.Stat(filepath.Join(binaryTmpDir, "barrygen", "mw0", "middleware.go"))
+		middlewareCopied = err == nil
+		_ = filepath.WalkDir(filepath.Join(binaryTmpDir, "site"), func(path string, d os.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				rel, _ := filepath.Rel(filepath.Join(binaryTmpDir, "site"), path)
+				embedded[filepath.ToSlash(rel)] = true
+			}
+			return nil
+		})
+		return exec.Command("true")
+	}
+
+	output := captureOutput(func() {
+		if err := runBuild(t, "--binary", "--out", "dist/site"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	if got := strings.Join(args, " "); !strings.HasPrefix(got, "build -o dist/site ") {
+		t.Errorf("unexpected build args %q", got)
+	}
+	for _, want := range []string{"//go:embed all:site", "barry.WithFS(site)", `Env:         "prod"`} {
+		if !strings.Contains(mainSrc, want) {
+			t.Errorf("expected main.go to contain %q, got:\n%s", want, mainSrc)
+		}
+	}
+	for _, want := range []string{`core.Register("routes/blog/_slug/index.server.go"`, `core.Register("api/ping/get.go"`, `mw0 "example.com/site/_barry-binary/barrygen/mw0"`} {
+		if !strings.Contains(handlersSrc, want) {
+			t.Errorf("expected registry to contain %q, got:\n%s", want, handlersSrc)
+		}
+	}
+	for _, want := range []string{"barry.config.yml", "routes/blog/_slug/index.html", "routes/blog/_slug/index.server.go", "api/ping/get.go", "public/css/app.css"} {
+		if !embedded[want] {
+			t.Errorf("expected %s to be embedded, got %v", want, embedded)
+		}
+	}
+	for _, unwanted := range []string{"routes/blog/_slug/index.server.so", "api/ping/get_test.go", "go.mod"} {
+		if embedded[unwanted] {
+			t.Errorf("expected %s to be left out", unwanted)
+		}
+	}
+	if !strings.Contains(output, "✅ Built dist/site") {
+		t.Errorf("unexpected output: %s", output)
+	}
+	if !middlewareCopied {
+		t.Error("expected middleware to be copied into the binary build directory")
+	}
+	if _, err := os.Stat(binaryTmpDir); !os.IsNotExist(err) {
+		t.Error("expected binary build directory to be removed")
+	}
+	if _, err := os.Stat("barrygen"); !os.IsNotExist(err) {
+		t.Error("expected no middleware copies in the project root")
+	}
+}
+
+func TestBuildCommand_BinaryBuildFails(t *testing.T) {
+	tmp := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	_ = os.Chdir(tmp)
+	_ = os.WriteFile("go.mod", []byte("module example.com/site\n"), 0644)
+
+	originalExec := buildExecCommand
+	defer func() { buildExecCommand = originalExec }()
+	buildExecCommand = func(string, ...string) *exec.Cmd { return exec.Command("false") }
+
+	var err error
+	captureOutput(func() {
+		err = runBuild(t, "--binary")
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to build binary") {
+		t.Errorf("expected build error, got %v", err)
+	}
+}
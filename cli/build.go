@@ -1,18 +1,30 @@
 package cli
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
 
+	"github.com/go-barry/barry/core"
 	"github.com/urfave/cli/v2"
 )
 
 var buildExecCommand = exec.Command
 var osWriteFileFunc = os.WriteFile
 var osMkdirAllFunc = os.MkdirAll
+var toolchainPluginEnvFunc = core.ToolchainPluginEnv
+var buildConcurrency = runtime.NumCPU
+
+const buildTmpDir = ".barry-tmp/build"
 
 func getGoModuleName() (string, error) {
 	data, err := os.ReadFile("go.mod")
@@ -28,74 +40,261 @@ func getGoModuleName() (string, error) {
 	return "", fmt.Errorf("module path not found in go.mod")
 }
 
+func pluginWrapperSource(importPath string, handlers []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `package main
+
+import user "%s"
+
+import (
+	"net/http"
+)
+`, importPath)
+
+	for _, name := range handlers {
+		fmt.Fprintf(&b, `
+func %[1]s(r *http.Request, p map[string]string) (interface{}, error) {
+	return user.%[1]s(r, p)
+}
+`, name)
+	}
+
+	return b.String()
+}
+
+type buildJob struct {
+	Source  string
+	Plugin  string
+	Kind    string
+	Wrapper string
+}
+
+type buildResult struct {
+	Status   string
+	Duration time.Duration
+	Entry    core.PluginEntry
+	Err      error
+}
+
 var BuildCommand = &cli.Command{
 	Name:  "build",
-	Usage: "Compile all .server.go files into .so plugins for production use",
+	Usage: "Compile all server files into .so plugins for production use",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{Name: "binary", Usage: "build one executable with handlers compiled in and the site embedded"},
+		&cli.StringFlag{Name: "out", Usage: "path of the executable built with --binary (default: module name)"},
+	},
 	Action: func(c *cli.Context) error {
 		modName, err := getGoModuleName()
 		if err != nil {
 			return fmt.Errorf("failed to determine module name from go.mod: %w", err)
 		}
 
-		for _, root := range []string{"routes", "api"} {
-			err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
-				if err != nil || d.IsDir() {
-					return nil
-				}
-				if filepath.Base(path) != "index.server.go" {
-					return nil
-				}
+		if c.Bool("binary") {
+			return buildBinary(modName, c.String("out"))
+		}
 
-				dir := filepath.Dir(path)
-				pluginOut := filepath.Join(dir, "index.server.so")
+		env, err := toolchainPluginEnvFunc(".")
+		if err != nil {
+			return fmt.Errorf("failed to inspect Go toolchain: %w", err)
+		}
 
-				relImport := strings.TrimPrefix(dir, root)
-				relImport = strings.TrimPrefix(relImport, string(filepath.Separator))
-				relImport = filepath.ToSlash(filepath.Join(root, relImport))
-				importPath := fmt.Sprintf("%s/%s", modName, relImport)
+		previous, _ := core.ReadPluginManifest(core.PluginManifestFile)
+		if previous != nil && (previous.GoVersion != env.GoVersion || !reflect.DeepEqual(previous.Modules, env.Modules)) {
+			previous = nil
+		}
 
-				tmpFile := filepath.Join(".barry-tmp", relImport, "plugin_wrapper.go")
+		jobs := collectBuildJobs()
+		cwd, _ := os.Getwd()
+		fmt.Println("📦 Building from:", cwd)
 
-				if err := osMkdirAllFunc(filepath.Dir(tmpFile), os.ModePerm); err != nil {
-					return fmt.Errorf("failed to create wrapper directory: %w", err)
+		results := make([]buildResult, len(jobs))
+		queue := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < max(1, min(buildConcurrency(), len(jobs))); w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range queue {
+					results[i] = runBuildJob(jobs[i], modName, previous)
 				}
+			}()
+		}
+		for i := range jobs {
+			queue <- i
+		}
+		close(queue)
+		wg.Wait()
 
-				wrapper := fmt.Sprintf(`package main
+		_ = os.RemoveAll(buildTmpDir)
+		for _, root := range []string{"routes", "api", "components"} {
+			_ = os.RemoveAll(filepath.Join(".barry-tmp", root))
+		}
 
-import user "%s"
+		manifest := &core.PluginManifest{GoVersion: env.GoVersion, Modules: env.Modules, Plugins: map[string]core.PluginEntry{}}
+		errs := []error{}
+		for i, result := range results {
+			if result.Err != nil {
+				errs = append(errs, result.Err)
+				continue
+			}
+			manifest.Plugins[filepath.ToSlash(jobs[i].Plugin)] = result.Entry
+		}
+		if err := core.WritePluginManifest(core.PluginManifestFile, manifest); err != nil {
+			return fmt.Errorf("failed to write %s: %w", core.PluginManifestFile, err)
+		}
 
-import (
-	"net/http"
-)
+		printBuildSummary(jobs, results)
 
-func HandleRequest(r *http.Request, p map[string]string) (map[string]interface{}, error) {
-	return user.HandleRequest(r, p)
+		if len(errs) > 0 {
+			return fmt.Errorf("%d of %d plugins failed to build: %w", len(errs), len(jobs), errors.Join(errs...))
+		}
+		fmt.Println("✅ All plugins built successfully.")
+		return nil
+	},
 }
-`, importPath)
 
-				if err := osWriteFileFunc(tmpFile, []byte(wrapper), 0644); err != nil {
-					return fmt.Errorf("failed to write wrapper for %s: %w", path, err)
-				}
+func collectBuildJobs() []buildJob {
+	jobs := []buildJob{}
+	for _, root := range []string{"routes", "api", "components"} {
+		_ = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
 
-				cmd := buildExecCommand("go", "build", "-buildmode=plugin", "-o", pluginOut, tmpFile)
-				cmd.Dir = "."
-				cmd.Stdout = os.Stdout
-				cmd.Stderr = os.Stderr
-				cwd, _ := os.Getwd()
-				fmt.Println("📦 Building from:", cwd)
-				fmt.Println("🔧 Building:", pluginOut)
-				if err := cmd.Run(); err != nil {
-					return fmt.Errorf("failed to build plugin for %s: %w", path, err)
+			base := filepath.Base(path)
+			kind := ""
+			switch {
+			case base == "_middleware.server.go":
+				kind = "middleware"
+			case base == "layout.server.go":
+				kind = "layout"
+			case root == "api":
+				if core.FindApiFile(filepath.Dir(path)) == path {
+					kind = "api"
 				}
+			case base == "index.server.go":
+				kind = "page"
+			case root == "components" && strings.HasSuffix(base, ".server.go") && !strings.HasPrefix(base, "_"):
+				kind = "component"
+			}
+			if kind == "" {
 				return nil
+			}
+
+			jobs = append(jobs, buildJob{
+				Source:  path,
+				Plugin:  strings.TrimSuffix(path, ".go") + ".so",
+				Kind:    kind,
+				Wrapper: filepath.Join(buildTmpDir, strings.TrimSuffix(path, ".go"), "plugin_wrapper.go"),
 			})
+			return nil
+		})
+	}
+	return jobs
+}
 
-			if err != nil {
-				return err
-			}
+func runBuildJob(job buildJob, modName string, previous *core.PluginManifest) buildResult {
+	start := time.Now()
+	result := buildResult{Status: "built"}
+
+	hash, err := core.PluginSourceHash(job.Source)
+	if err != nil {
+		result.Status, result.Err = "failed", fmt.Errorf("failed to hash sources for %s: %w", job.Source, err)
+		return result
+	}
+	deps, err := core.PluginDepsHash(job.Source)
+	if err != nil {
+		result.Status, result.Err = "failed", fmt.Errorf("failed to hash dependencies for %s: %w", job.Source, err)
+		return result
+	}
+	result.Entry = core.PluginEntry{Source: filepath.ToSlash(job.Source), Hash: hash, Deps: deps}
+
+	if previous != nil && previous.Plugins[filepath.ToSlash(job.Plugin)] == result.Entry {
+		if _, err := os.Stat(job.Plugin); err == nil {
+			result.Status = "skipped"
+			return result
 		}
+	}
 
-		fmt.Println("✅ All plugins built successfully.")
-		return nil
-	},
+	wrapper, err := buildWrapperSource(job, modName)
+	if err != nil {
+		result.Status, result.Err = "failed", err
+		return result
+	}
+
+	if err := osMkdirAllFunc(filepath.Dir(job.Wrapper), os.ModePerm); err != nil {
+		result.Status, result.Err = "failed", fmt.Errorf("failed to create wrapper directory: %w", err)
+		return result
+	}
+	if err := osWriteFileFunc(job.Wrapper, wrapper, 0644); err != nil {
+		result.Status, result.Err = "failed", fmt.Errorf("failed to write wrapper for %s: %w", job.Source, err)
+		return result
+	}
+
+	fmt.Println("🔧 Building:", job.Plugin)
+	var output bytes.Buffer
+	cmd := buildExecCommand("go", "build", "-buildmode=plugin", "-o", job.Plugin, job.Wrapper)
+	cmd.Dir = "."
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		result.Status, result.Err = "failed", fmt.Errorf("failed to build plugin for %s: %w\n%s", job.Source, err, strings.TrimSpace(output.String()))
+	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+func buildWrapperSource(job buildJob, modName string) ([]byte, error) {
+	dir := filepath.Dir(job.Source)
+	importPath := modName + "/" + filepath.ToSlash(dir)
+
+	if job.Kind == "middleware" || core.CheckImportable(dir) != nil {
+		wrapper, err := core.MainPackageSource(job.Source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s %s: %w", job.Kind, job.Source, err)
+		}
+		return wrapper, nil
+	}
+
+	switch job.Kind {
+	case "component":
+		handlers, err := core.DetectComponentHandlers(dir)
+		if err != nil || len(handlers) == 0 {
+			return nil, fmt.Errorf("no component handlers found for %s", job.Source)
+		}
+		return []byte(pluginWrapperSource(importPath, handlers)), nil
+	}
+
+	handlers, err := core.DetectHandlers(dir)
+	if err != nil || len(handlers) == 0 {
+		handlers = []string{"HandleRequest"}
+	}
+	return []byte(pluginWrapperSource(importPath, handlers)), nil
+}
+
+func printBuildSummary(jobs []buildJob, results []buildResult) {
+	if len(jobs) == 0 {
+		fmt.Println("No server files to build.")
+		return
+	}
+
+	counts := map[string]int{}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nSTATUS\tKIND\tSOURCE\tTIME")
+	for i, result := range results {
+		counts[result.Status]++
+		duration := "-"
+		if result.Status != "skipped" {
+			duration = result.Duration.Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", result.Status, jobs[i].Kind, filepath.ToSlash(jobs[i].Source), duration)
+	}
+	tw.Flush()
+	fmt.Printf("\n⏱️ %d built, %d skipped, %d failed\n", counts["built"], counts["skipped"], counts["failed"])
+
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Printf("❌ %v\n", result.Err)
+		}
+	}
 }
@@ -2,13 +2,30 @@ package cli
 
 import (
 	"errors"
+	"flag"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+
+	"github.com/go-barry/barry/core"
+	"github.com/urfave/cli/v2"
 )
 
+func runBuild(t *testing.T, args ...string) error {
+	t.Helper()
+	set := flag.NewFlagSet("build", flag.ContinueOnError)
+	for _, f := range BuildCommand.Flags {
+		_ = f.Apply(set)
+	}
+	_ = set.Parse(args)
+	return BuildCommand.Action(cli.NewContext(cli.NewApp(), set, nil))
+}
+
 func TestGetGoModuleName_Success(t *testing.T) {
 	tmp := t.TempDir()
 	goModPath := filepath.Join(tmp, "go.mod")
@@ -75,7 +92,7 @@ func TestBuildCommand_WriteFileFails(t *testing.T) {
 	defer os.Chdir(origDir)
 	os.Chdir(tmp)
 
-	err := BuildCommand.Action(nil)
+	err := runBuild(t)
 	if err == nil || !strings.Contains(err.Error(), "failed to write wrapper") {
 		t.Errorf("expected wrapper write error, got %v", err)
 	}
@@ -99,7 +116,7 @@ func TestBuildCommand_CommandFails(t *testing.T) {
 	defer os.Chdir(oldWD)
 	_ = os.Chdir(tmp)
 
-	err := BuildCommand.Action(nil)
+	err := runBuild(t)
 	if err == nil || !strings.Contains(err.Error(), "failed to build plugin") {
 		t.Errorf("expected build error, got %v", err)
 	}
@@ -123,7 +140,7 @@ func TestBuildCommand_MkdirFails(t *testing.T) {
 	defer os.Chdir(oldWD)
 	_ = os.Chdir(tmp)
 
-	err := BuildCommand.Action(nil)
+	err := runBuild(t)
 	if err == nil || !strings.Contains(err.Error(), "failed to create wrapper directory") {
 		t.Errorf("expected mkdir fail error, got: %v", err)
 	}
@@ -163,13 +180,22 @@ func TestBuildCommand_Success(t *testing.T) {
 	defer os.Chdir(origDir)
 	os.Chdir(tmp)
 
-	err := BuildCommand.Action(nil)
+	err := runBuild(t)
 	if err != nil {
 		t.Errorf("expected no error, got: %v", err)
 	}
 	if len(capturedCmdArgs) == 0 {
 		t.Error("expected build command to run")
 	}
+
+	manifest, err := core.ReadPluginManifest(core.PluginManifestFile)
+	if err != nil {
+		t.Fatalf("expected plugin manifest: %v", err)
+	}
+	entry := manifest.Plugins["routes/demo/index.server.so"]
+	if manifest.GoVersion == "" || entry.Source != "routes/demo/index.server.go" || entry.Hash == "" {
+		t.Errorf("unexpected manifest %+v", manifest)
+	}
 }
 
 func TestBuildCommand_ModuleNameFails(t *testing.T) {
@@ -179,7 +205,7 @@ func TestBuildCommand_ModuleNameFails(t *testing.T) {
 	defer os.Chdir(origDir)
 	_ = os.Chdir(tmp)
 
-	err := BuildCommand.Action(nil)
+	err := runBuild(t)
 	if err == nil || !strings.Contains(err.Error(), "failed to determine module name from go.mod") {
 		t.Errorf("expected module name error, got: %v", err)
 	}
@@ -219,8 +245,271 @@ func TestBuildCommand_SkipsNonIndexServerGoFiles(t *testing.T) {
 	defer os.Chdir(origDir)
 	_ = os.Chdir(tmp)
 
-	err := BuildCommand.Action(nil)
+	err := runBuild(t)
 	if err != nil {
 		t.Errorf("expected no error, got: %v", err)
 	}
 }
+
+func TestPluginWrapperSource_ExportsEachHandler(t *testing.T) {
+	src := pluginWrapperSource("github.com/test/app/api/users", []string{"HandleGet", "HandlePost"})
+
+	for _, expected := range []string{
+		`import user "github.com/test/app/api/users"`,
+		"func HandleGet(r *http.Request, p map[string]string) (interface{}, error)",
+		"return user.HandlePost(r, p)",
+	} {
+		if !strings.Contains(src, expected) {
+			t.Errorf("expected wrapper to contain %q, got:\n%s", expected, src)
+		}
+	}
+}
+
+func TestBuildCommand_BuildsMiddlewareAsMainPackage(t *testing.T) {
+	tmp := t.TempDir()
+	_ = os.WriteFile(filepath.Join(tmp, "go.mod"), []byte("module github.com/test/middleware\n"), 0644)
+
+	routeDir := filepath.Join(tmp, "routes", "admin")
+	_ = os.MkdirAll(routeDir, 0755)
+	_ = os.WriteFile(filepath.Join(routeDir, "_middleware.server.go"), []byte("package admin\n\nfunc Middleware() {}\n"), 0644)
+
+	originalWrite := osWriteFileFunc
+	originalMkdir := osMkdirAllFunc
+	originalExec := buildExecCommand
+	defer func() {
+		osWriteFileFunc = originalWrite
+		osMkdirAllFunc = originalMkdir
+		buildExecCommand = originalExec
+	}()
+
+	var written string
+	var capturedArgs []string
+	osWriteFileFunc = func(path string, data []byte, perm os.FileMode) error {
+		written = string(data)
+		return nil
+	}
+	osMkdirAllFunc = func(path string, perm os.FileMode) error {
+		return nil
+	}
+	buildExecCommand = func(name string, args ...string) *exec.Cmd {
+		capturedArgs = args
+		return exec.Command("true")
+	}
+
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	_ = os.Chdir(tmp)
+
+	if err := runBuild(t); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !strings.Contains(written, "package main") {
+		t.Errorf("expected middleware rewritten to package main, got: %s", written)
+	}
+	if !strings.Contains(strings.Join(capturedArgs, " "), filepath.Join("routes", "admin", "_middleware.server.so")) {
+		t.Errorf("expected middleware plugin output, got %v", capturedArgs)
+	}
+}
+
+func TestBuildWrapperSource_InlinesServerFilesGoCantImport(t *testing.T) {
+	tmp := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	_ = os.Chdir(tmp)
+
+	dir := filepath.Join("routes", "list", "_[page]")
+	_ = os.MkdirAll(dir, 0755)
+	_ = os.WriteFile(filepath.Join(dir, "index.server.go"), []byte("package page\n\nfunc HandleRequest() {}\n"), 0644)
+
+	wrapper, err := buildWrapperSource(buildJob{Source: filepath.Join(dir, "index.server.go"), Kind: "page"}, "example.com/site")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(string(wrapper), "package main") || strings.Contains(string(wrapper), "example.com/site") {
+		t.Errorf("expected server file inlined as package main, got:\n%s", wrapper)
+	}
+}
+
+func setupIncrementalBuild(t *testing.T, files map[string]string) *[]string {
+	t.Helper()
+	tmp := t.TempDir()
+	origDir, _ := os.Getwd()
+	_ = os.Chdir(tmp)
+
+	originalExec := buildExecCommand
+	originalEnv := toolchainPluginEnvFunc
+	t.Cleanup(func() {
+		_ = os.Chdir(origDir)
+		buildExecCommand = originalExec
+		toolchainPluginEnvFunc = originalEnv
+	})
+
+	files["go.mod"] = "module github.com/test/incremental\n"
+	for path, content := range files {
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		_ = os.WriteFile(path, []byte(content), 0644)
+	}
+
+	toolchainPluginEnvFunc = func(string) (core.PluginEnv, error) {
+		return core.PluginEnv{GoVersion: "go1.24.3", Modules: map[string]string{}}, nil
+	}
+
+	var mu sync.Mutex
+	built := []string{}
+	buildExecCommand = func(name string, args ...string) *exec.Cmd {
+		out := args[3]
+		mu.Lock()
+		built = append(built, filepath.ToSlash(out))
+		mu.Unlock()
+		return exec.Command("touch", out)
+	}
+	return &built
+}
+
+func TestBuildCommand_SkipsUnchangedPlugins(t *testing.T) {
+	built := setupIncrementalBuild(t, map[string]string{
+		"routes/blog/index.server.go":  "package blog\n\nimport \"github.com/test/incremental/lib\"\n\nvar _ = lib.Name\n",
+		"routes/about/index.server.go": "package about\n",
+		"lib/lib.go":                   "package lib\n\nconst Name = \"a\"\n",
+	})
+
+	var output string
+	build := func() {
+		*built = (*built)[:0]
+		output = captureOutput(func() {
+			if err := runBuild(t); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		sort.Strings(*built)
+	}
+
+	build()
+	if len(*built) != 2 {
+		t.Fatalf("expected both plugins built, got %v", *built)
+	}
+
+	build()
+	if len(*built) != 0 {
+		t.Errorf("expected unchanged plugins to be skipped, got %v", *built)
+	}
+	if !strings.Contains(output, "0 built, 2 skipped, 0 failed") {
+		t.Errorf("expected summary of skipped plugins, got:\n%s", output)
+	}
+
+	_ = os.WriteFile("routes/about/index.server.go", []byte("package about\n\nfunc helper() {}\n"), 0644)
+	build()
+	if !reflect.DeepEqual(*built, []string{"routes/about/index.server.so"}) {
+		t.Errorf("expected only changed source rebuilt, got %v", *built)
+	}
+
+	_ = os.WriteFile("lib/lib.go", []byte("package lib\n\nconst Name = \"b\"\n"), 0644)
+	build()
+	if !reflect.DeepEqual(*built, []string{"routes/blog/index.server.so"}) {
+		t.Errorf("expected plugin with changed dependency rebuilt, got %v", *built)
+	}
+
+	_ = os.Remove("routes/blog/index.server.so")
+	build()
+	if !reflect.DeepEqual(*built, []string{"routes/blog/index.server.so"}) {
+		t.Errorf("expected missing plugin rebuilt, got %v", *built)
+	}
+
+	toolchainPluginEnvFunc = func(string) (core.PluginEnv, error) {
+		return core.PluginEnv{GoVersion: "go1.25.0"}, nil
+	}
+	build()
+	if len(*built) != 2 {
+		t.Errorf("expected toolchain change to rebuild everything, got %v", *built)
+	}
+}
+
+func TestBuildCommand_CoversEveryHandlerType(t *testing.T) {
+	built := setupIncrementalBuild(t, map[string]string{
+		"routes/index.server.go":              "package routes\n",
+		"routes/admin/_middleware.server.go":  "package admin\n\nfunc Middleware() {}\n",
+		"api/users/index.go":                  "package users\n\nfunc HandleGet() {}\n",
+		"api/posts/get.go":                    "package posts\n\nfunc HandleGet() {}\n",
+		"api/posts/helpers.go":                "package posts\n",
+		"components/layouts/layout.server.go": "package layouts\n",
+		"components/widgets/latest.server.go": "package widgets\n\nfunc HandleLatest() {}\n",
+	})
+
+	output := captureOutput(func() {
+		if err := runBuild(t); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	sort.Strings(*built)
+
+	expected := []string{
+		"api/posts/get.so",
+		"api/users/index.so",
+		"components/layouts/layout.server.so",
+		"components/widgets/latest.server.so",
+		"routes/admin/_middleware.server.so",
+		"routes/index.server.so",
+	}
+	if !reflect.DeepEqual(*built, expected) {
+		t.Errorf("expected %v, got %v", expected, *built)
+	}
+	for _, want := range []string{"STATUS", "built   api", "6 built, 0 skipped, 0 failed"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestBuildCommand_ReportsFailuresAndKeepsBuilding(t *testing.T) {
+	built := setupIncrementalBuild(t, map[string]string{
+		"routes/index.server.go":      "package routes\n",
+		"routes/blog/index.server.go": "package blog\n",
+	})
+	originalExec := buildExecCommand
+	buildExecCommand = func(name string, args ...string) *exec.Cmd {
+		if strings.Contains(args[3], "blog") {
+			return exec.Command("false")
+		}
+		return originalExec(name, args...)
+	}
+
+	var err error
+	output := captureOutput(func() {
+		err = runBuild(t)
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 plugins failed to build") {
+		t.Fatalf("expected build failure, got %v", err)
+	}
+	if len(*built) != 1 || !strings.Contains(output, "1 built, 0 skipped, 1 failed") {
+		t.Errorf("expected the other plugin to build, got %v:\n%s", *built, output)
+	}
+
+	manifest, _ := core.ReadPluginManifest(core.PluginManifestFile)
+	if _, ok := manifest.Plugins["routes/blog/index.server.so"]; ok {
+		t.Error("expected failed plugin to be left out of the manifest")
+	}
+}
+
+func TestBuildCommand_CleansStrayWrappers(t *testing.T) {
+	setupIncrementalBuild(t, map[string]string{
+		"routes/index.server.go":                         "package routes\n",
+		".barry-tmp/routes/old/plugin_wrapper.go":        "package main\n",
+		".barry-tmp/workers/abc/main.go":                 "package main\n",
+		".barry-tmp/build/routes/gone/plugin_wrapper.go": "package main\n",
+	})
+
+	captureOutput(func() {
+		if err := runBuild(t); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	for _, path := range []string{".barry-tmp/routes", ".barry-tmp/build"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", path)
+		}
+	}
+	if _, err := os.Stat(".barry-tmp/workers/abc/main.go"); err != nil {
+		t.Errorf("expected worker sources to be kept: %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 	"text/template"
+	"text/template/parse"
 
 	"github.com/go-barry/barry/core"
 	"github.com/urfave/cli/v2"
@@ -61,7 +62,7 @@ var CheckCommand = &cli.Command{
 			}
 
 			var tmpl *template.Template
-			tmpl = template.New(filepath.Base(files[0])).Funcs(core.BarryTemplateFuncs("dev", "cache"))
+			tmpl = template.New(filepath.Base(files[0])).Funcs(core.CheckTemplateFuncs())
 			tmpl, err = tmpl.ParseFiles(files...)
 
 			if err != nil {
@@ -70,6 +71,20 @@ var CheckCommand = &cli.Command{
 				return nil
 			}
 
+			urlErrors := []error{}
+			for _, t := range tmpl.Templates() {
+				if t.Tree != nil {
+					urlErrors = append(urlErrors, checkURLCalls(t.Tree.Root)...)
+				}
+			}
+			if len(urlErrors) > 0 {
+				failed = true
+				for _, err := range urlErrors {
+					fmt.Printf("❌ %s → url error: %v\n", rel, err)
+				}
+				return nil
+			}
+
 			var buf bytes.Buffer
 			err = tmpl.ExecuteTemplate(&buf, "layout", map[string]interface{}{})
 			if err != nil {
@@ -90,3 +105,81 @@ var CheckCommand = &cli.Command{
 		return nil
 	},
 }
+
+func checkURLCalls(node parse.Node) []error {
+	errs := []error{}
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return errs
+		}
+		for _, child := range n.Nodes {
+			errs = append(errs, checkURLCalls(child)...)
+		}
+	case *parse.ActionNode:
+		errs = append(errs, checkURLCalls(n.Pipe)...)
+	case *parse.IfNode:
+		errs = append(errs, checkBranchURLCalls(&n.BranchNode)...)
+	case *parse.RangeNode:
+		errs = append(errs, checkBranchURLCalls(&n.BranchNode)...)
+	case *parse.WithNode:
+		errs = append(errs, checkBranchURLCalls(&n.BranchNode)...)
+	case *parse.TemplateNode:
+		errs = append(errs, checkURLCalls(n.Pipe)...)
+	case *parse.PipeNode:
+		if n == nil {
+			return errs
+		}
+		for _, cmd := range n.Cmds {
+			errs = append(errs, checkURLCalls(cmd)...)
+		}
+	case *parse.CommandNode:
+		if err := checkURLCommand(n); err != nil {
+			errs = append(errs, err)
+		}
+		for _, arg := range n.Args {
+			errs = append(errs, checkURLCalls(arg)...)
+		}
+	}
+
+	return errs
+}
+
+func checkBranchURLCalls(n *parse.BranchNode) []error {
+	errs := checkURLCalls(n.Pipe)
+	errs = append(errs, checkURLCalls(n.List)...)
+	if n.ElseList != nil {
+		errs = append(errs, checkURLCalls(n.ElseList)...)
+	}
+	return errs
+}
+
+func checkURLCommand(cmd *parse.CommandNode) error {
+	if len(cmd.Args) < 2 {
+		return nil
+	}
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	if !ok || ident.Ident != "url" {
+		return nil
+	}
+	route, ok := cmd.Args[1].(*parse.StringNode)
+	if !ok {
+		return nil
+	}
+
+	rest := cmd.Args[2:]
+	if len(rest) == 1 {
+		return core.ValidateURL(route.Text, nil)
+	}
+
+	keys := []string{}
+	for i := 0; i < len(rest); i += 2 {
+		key, ok := rest[i].(*parse.StringNode)
+		if !ok {
+			return core.ValidateURL(route.Text, nil)
+		}
+		keys = append(keys, key.Text)
+	}
+	return core.ValidateURL(route.Text, keys)
+}
@@ -165,3 +165,61 @@ func TestCheckCommand_ExecError(t *testing.T) {
 		t.Fatalf("expected cli.Exit code 1, got: %v", appErr)
 	}
 }
+
+func TestCheckCommand_URLErrors(t *testing.T) {
+	tempDir := t.TempDir()
+
+	blogDir := filepath.Join(tempDir, "routes", "blog", "_slug")
+	if err := os.MkdirAll(blogDir, 0755); err != nil {
+		t.Fatalf("Failed to create routes dir: %v", err)
+	}
+	_ = os.WriteFile(filepath.Join(blogDir, "index.html"), []byte(`{{ define "layout" }}post{{ end }}`), 0644)
+
+	pageDir := filepath.Join(tempDir, "routes", "links")
+	_ = os.MkdirAll(pageDir, 0755)
+	links := `{{ define "layout" }}
+<a href="{{ url "blog/_slug" "slug" .Slug }}">ok</a>
+{{ if .Never }}<a href="{{ url "blog/_slug" }}">missing</a>{{ end }}
+<a href="{{ url "nope" }}">unknown</a>
+{{ end }}`
+	_ = os.WriteFile(filepath.Join(pageDir, "index.html"), []byte(links), 0644)
+
+	originalStdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe failed: %v", err)
+	}
+	os.Stdout = w
+
+	originalCWD, _ := os.Getwd()
+	defer os.Chdir(originalCWD)
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+
+	app := &cli.App{
+		Commands:       []*cli.Command{CheckCommand},
+		ExitErrHandler: func(c *cli.Context, err error) {},
+	}
+	appErr := app.Run([]string{"cli", "check"})
+
+	w.Close()
+	os.Stdout = originalStdout
+
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	output := buf.String()
+
+	if !strings.Contains(output, `❌ /links → url error: route "blog/_slug": missing param "slug"`) {
+		t.Errorf("expected missing param error, got:\n%s", output)
+	}
+	if !strings.Contains(output, `❌ /links → url error: unknown route "nope"`) {
+		t.Errorf("expected unknown route error, got:\n%s", output)
+	}
+	if !strings.Contains(output, "✅ /blog/_slug") {
+		t.Errorf("expected valid route to pass, got:\n%s", output)
+	}
+	if _, ok := appErr.(cli.ExitCoder); !ok {
+		t.Errorf("expected exit error, got: %v", appErr)
+	}
+}
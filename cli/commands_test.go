@@ -9,7 +9,7 @@ import (
 
 var recordedConfig *barry.RuntimeConfig
 
-func mockStart(cfg barry.RuntimeConfig) {
+func mockStart(cfg barry.RuntimeConfig, opts ...barry.Option) {
 	recordedConfig = &cfg
 }
 
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/go-barry/barry/core"
+	"github.com/urfave/cli/v2"
+)
+
+var DoctorCommand = &cli.Command{
+	Name:  "doctor",
+	Usage: "Check built plugins against the current Go toolchain, modules and sources",
+	Action: func(c *cli.Context) error {
+		manifest, err := core.ReadPluginManifest(core.PluginManifestFile)
+		if err != nil {
+			plugins := core.FindPlugins()
+			if os.IsNotExist(err) && len(plugins) == 0 {
+				fmt.Println("🧼 No plugins built, server files run as subprocesses.")
+				return nil
+			}
+			fmt.Printf("❌ %s: %v\n", core.PluginManifestFile, err)
+			return cli.Exit(fmt.Sprintf("%d plugins cannot be validated, run barry build", len(plugins)), 1)
+		}
+
+		env, err := toolchainPluginEnvFunc(".")
+		if err != nil {
+			return fmt.Errorf("failed to inspect Go toolchain: %w", err)
+		}
+		fmt.Printf("📍 Plugins built with %s, toolchain is %s\n", manifest.GoVersion, env.GoVersion)
+
+		issues := core.CheckPlugins(manifest, env)
+		failed := map[string]bool{}
+		for _, issue := range issues {
+			failed[issue.Plugin] = true
+			fmt.Printf("❌ %s → %s\n", issue.Plugin, issue.Reason)
+		}
+
+		ok := []string{}
+		for soPath := range manifest.Plugins {
+			if !failed[soPath] {
+				ok = append(ok, soPath)
+			}
+		}
+		sort.Strings(ok)
+		for _, soPath := range ok {
+			fmt.Printf("✅ %s\n", soPath)
+		}
+
+		if len(issues) > 0 {
+			return cli.Exit(fmt.Sprintf("%d plugins need a rebuild, run barry build", len(issues)), 1)
+		}
+		fmt.Println("✅ All plugins match the current toolchain and sources.")
+		return nil
+	},
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-barry/barry/core"
+	"github.com/urfave/cli/v2"
+)
+
+func runDoctor(t *testing.T, env core.PluginEnv, files map[string]string) (string, error) {
+	t.Helper()
+	tmp := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	_ = os.Chdir(tmp)
+
+	original := toolchainPluginEnvFunc
+	defer func() { toolchainPluginEnvFunc = original }()
+	toolchainPluginEnvFunc = func(string) (core.PluginEnv, error) { return env, nil }
+
+	for path, content := range files {
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		_ = os.WriteFile(path, []byte(content), 0644)
+	}
+
+	var err error
+	output := captureOutput(func() {
+		set := flag.NewFlagSet("doctor", flag.ContinueOnError)
+		err = DoctorCommand.Action(cli.NewContext(cli.NewApp(), set, nil))
+	})
+	return output, err
+}
+
+func TestDoctorCommand_NoPlugins(t *testing.T) {
+	output, err := runDoctor(t, core.PluginEnv{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "No plugins built") {
+		t.Errorf("unexpected output: %s", output)
+	}
+}
+
+func TestDoctorCommand_MissingManifest(t *testing.T) {
+	output, err := runDoctor(t, core.PluginEnv{}, map[string]string{"routes/index.server.so": "so"})
+	if err == nil || !strings.Contains(err.Error(), "run barry build") {
+		t.Fatalf("expected rebuild error, got %v", err)
+	}
+	if !strings.Contains(output, "❌ "+core.PluginManifestFile) {
+		t.Errorf("unexpected output: %s", output)
+	}
+}
+
+func TestDoctorCommand_ReportsEachPlugin(t *testing.T) {
+	manifest := `{
+  "goVersion": "go1.24.3",
+  "plugins": {
+    "routes/index.server.so": {"source": "routes/index.server.go", "hash": "stale"}
+  }
+}`
+	files := map[string]string{
+		core.PluginManifestFile:  manifest,
+		"routes/index.server.go": "package routes\n\nfunc HandleRequest() {}\n",
+		"routes/index.server.so": "so",
+	}
+
+	output, err := runDoctor(t, core.PluginEnv{GoVersion: "go1.25.0"}, files)
+	if err == nil {
+		t.Fatal("expected error for incompatible plugin")
+	}
+	if !strings.Contains(output, "❌ routes/index.server.so → built with go1.24.3, running go1.25.0") {
+		t.Errorf("expected go version issue, got: %s", output)
+	}
+
+	output, err = runDoctor(t, core.PluginEnv{GoVersion: "go1.24.3"}, files)
+	if err == nil || !strings.Contains(output, "sources changed since build") {
+		t.Errorf("expected changed sources, got %v: %s", err, output)
+	}
+}
+
+func TestDoctorCommand_AllPluginsCompatible(t *testing.T) {
+	tmp := t.TempDir()
+	src := filepath.Join(tmp, "index.server.go")
+	_ = os.WriteFile(src, []byte("package routes\n\nfunc HandleRequest() {}\n"), 0644)
+	hash, _ := core.PluginSourceHash(src)
+
+	manifest := `{"goVersion": "go1.24.3", "plugins": {"routes/index.server.so": {"source": "routes/index.server.go", "hash": "` + hash + `"}}}`
+	output, err := runDoctor(t, core.PluginEnv{GoVersion: "go1.24.3"}, map[string]string{
+		core.PluginManifestFile:  manifest,
+		"routes/index.server.go": "package routes\n\nfunc HandleRequest() {}\n",
+		"routes/index.server.so": "so",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "✅ routes/index.server.so") || !strings.Contains(output, "All plugins match") {
+		t.Errorf("unexpected output: %s", output)
+	}
+}
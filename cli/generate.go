@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-barry/barry/core"
+	"github.com/urfave/cli/v2"
+)
+
+const generatedMiddlewareDir = "barrygen"
+
+type registryEntry struct {
+	Key      string
+	Alias    string
+	Import   string
+	Handlers []string
+}
+
+var GenerateCommand = &cli.Command{
+	Name:  "generate",
+	Usage: "Write a Go file that registers every server handler for single-binary builds",
+	Flags: []cli.Flag{
+		&cli.StringFlag{Name: "out", Value: "barry_handlers.go", Usage: "file to write the registry to"},
+		&cli.StringFlag{Name: "package", Value: "main", Usage: "package name of the generated file"},
+	},
+	Action: func(c *cli.Context) error {
+		modName, err := getGoModuleName()
+		if err != nil {
+			return fmt.Errorf("failed to determine module name from go.mod: %w", err)
+		}
+
+		config := core.LoadConfig("barry.config.yml")
+		entries, err := collectRegistryEntries(modName, core.LoadRouteTable(*config), generatedMiddlewareDir)
+		if err != nil {
+			return err
+		}
+
+		src, err := registrySource(c.String("package"), entries)
+		if err != nil {
+			return err
+		}
+
+		out := c.String("out")
+		if err := osWriteFileFunc(out, src, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", out, err)
+		}
+
+		fmt.Printf("✅ Registered %d server files in %s\n", len(entries), out)
+		return nil
+	},
+}
+
+func collectRegistryEntries(modName string, table []core.RouteInfo, middlewareDir string) ([]registryEntry, error) {
+	entries := []registryEntry{}
+	middleware := map[string]bool{}
+
+	serverFiles := []string{}
+	for _, route := range table {
+		for _, path := range route.Middleware {
+			middleware[path] = true
+		}
+		if route.ServerFile != "" && !route.Unreachable {
+			serverFiles = append(serverFiles, route.ServerFile)
+		}
+	}
+	components := map[string]bool{}
+	_ = filepath.WalkDir("components", func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(d.Name(), ".server.go") {
+			return nil
+		}
+		serverFiles = append(serverFiles, path)
+		components[path] = d.Name() != "layout.server.go"
+		return nil
+	})
+
+	for _, path := range serverFiles {
+		dir := filepath.Dir(path)
+		if err := core.CheckImportable(dir); err != nil {
+			return nil, fmt.Errorf("failed to register %s: %w", path, err)
+		}
+		detect := core.DetectHandlers
+		if components[path] {
+			detect = core.DetectComponentHandlers
+		}
+		handlers, err := detect(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect %s: %w", dir, err)
+		}
+		if len(handlers) == 0 {
+			fmt.Printf("⚠️ Skipping %s: no handlers found\n", path)
+			continue
+		}
+
+		entries = append(entries, registryEntry{
+			Key:      filepath.ToSlash(path),
+			Alias:    fmt.Sprintf("h%d", len(entries)),
+			Import:   modName + "/" + filepath.ToSlash(dir),
+			Handlers: handlers,
+		})
+	}
+
+	if err := os.RemoveAll(middlewareDir); err != nil {
+		return nil, fmt.Errorf("failed to clear %s: %w", middlewareDir, err)
+	}
+
+	paths := make([]string, 0, len(middleware))
+	for path := range middleware {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for i, path := range paths {
+		pkg := fmt.Sprintf("mw%d", i)
+		src, err := core.RenamePackageSource(path, pkg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read middleware %s: %w", path, err)
+		}
+
+		dir := filepath.Join(middlewareDir, pkg)
+		if err := osMkdirAllFunc(dir, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		if err := osWriteFileFunc(filepath.Join(dir, "middleware.go"), src, 0644); err != nil {
+			return nil, fmt.Errorf("failed to copy middleware %s: %w", path, err)
+		}
+
+		entries = append(entries, registryEntry{
+			Key:      filepath.ToSlash(path),
+			Alias:    pkg,
+			Import:   modName + "/" + filepath.ToSlash(middlewareDir) + "/" + pkg,
+			Handlers: []string{"Middleware"},
+		})
+	}
+
+	return entries, nil
+}
+
+func registrySource(pkg string, entries []registryEntry) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by barry generate. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	b.WriteString("\t\"net/http\"\n\n\t\"github.com/go-barry/barry/core\"\n")
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "\t%s %q\n", entry.Alias, entry.Import)
+	}
+	b.WriteString(")\n\nfunc init() {\n")
+
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "\tcore.Register(%q, map[string]core.HandlerFunc{\n", entry.Key)
+		for _, name := range entry.Handlers {
+			fmt.Fprintf(&b, "\t\t%q: func(r *http.Request, p map[string]string) (interface{}, error) {\n\t\t\treturn %s.%s(r, p)\n\t\t},\n", name, entry.Alias, name)
+		}
+		b.WriteString("\t})\n")
+	}
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format registry: %w\n%s", err, strings.TrimSpace(b.String()))
+	}
+	return src, nil
+}
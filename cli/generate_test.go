@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func runGenerate(t *testing.T, args ...string) error {
+	t.Helper()
+	set := flag.NewFlagSet("generate", flag.ContinueOnError)
+	for _, f := range GenerateCommand.Flags {
+		_ = f.Apply(set)
+	}
+	_ = set.Parse(args)
+	return GenerateCommand.Action(cli.NewContext(cli.NewApp(), set, nil))
+}
+
+func TestGenerateCommand_WritesRegistry(t *testing.T) {
+	tmp := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	_ = os.Chdir(tmp)
+
+	files := map[string]string{
+		"go.mod":                              "module example.com/site\n",
+		"routes/blog/_slug/index.html":        `{{ define "content" }}{{ end }}`,
+		"routes/blog/_slug/index.server.go":   "package slug\n\nfunc HandleRequest() {}\n",
+		"routes/admin/index.html":             `{{ define "content" }}{{ end }}`,
+		"routes/admin/_middleware.server.go":  "package admin\n\nfunc Middleware() {}\n",
+		"routes/about/index.html":             `{{ define "content" }}{{ end }}`,
+		"routes/about/index.server.go":        "package about\n\nfunc helper() {}\n",
+		"api/users/post.go":                   "package users\n\nfunc HandlePost() {}\nfunc HandleGet() {}\n",
+		"components/layouts/base.html":        `{{ define "layout" }}{{ end }}`,
+		"components/layouts/layout.server.go": "package layouts\n\nfunc HandleRequest() {}\n",
+		"components/widgets/latest.server.go": "package widgets\n\nfunc HandleLatestPosts() {}\n",
+	}
+	for path, content := range files {
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		_ = os.WriteFile(path, []byte(content), 0644)
+	}
+
+	output := captureOutput(func() {
+		if err := runGenerate(t); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	data, err := os.ReadFile("barry_handlers.go")
+	if err != nil {
+		t.Fatalf("expected registry file: %v", err)
+	}
+	src := string(data)
+
+	for _, want := range []string{
+		"package main",
+		`h0 "example.com/site/routes/blog/_slug"`,
+		`core.Register("routes/blog/_slug/index.server.go"`,
+		`"HandleRequest": func(r *http.Request, p map[string]string) (interface{}, error) {`,
+		"return h0.HandleRequest(r, p)",
+		`core.Register("api/users/post.go"`,
+		"return h1.HandleGet(r, p)",
+		"return h1.HandlePost(r, p)",
+		`mw0 "example.com/site/barrygen/mw0"`,
+		`core.Register("routes/admin/_middleware.server.go"`,
+		`"example.com/site/components/layouts"`,
+		`core.Register("components/layouts/layout.server.go"`,
+		`core.Register("components/widgets/latest.server.go"`,
+		".HandleLatestPosts(r, p)",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("expected registry to contain %q, got:\n%s", want, src)
+		}
+	}
+	if strings.Contains(src, "routes/about") {
+		t.Errorf("expected server file without handlers to be skipped, got:\n%s", src)
+	}
+	if !strings.Contains(output, "Skipping routes/about/index.server.go") {
+		t.Errorf("expected skip warning, got %q", output)
+	}
+
+	mw, err := os.ReadFile(filepath.Join("barrygen", "mw0", "middleware.go"))
+	if err != nil || !strings.HasPrefix(string(mw), "package mw0") {
+		t.Errorf("expected middleware copy in package mw0, got %q (%v)", mw, err)
+	}
+}
+
+func TestGenerateCommand_RejectsServerFilesGoCantImport(t *testing.T) {
+	tmp := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	_ = os.Chdir(tmp)
+
+	files := map[string]string{
+		"go.mod":                              "module example.com/site\n",
+		"routes/list/_[page]/index.html":      `{{ define "content" }}{{ end }}`,
+		"routes/list/_[page]/index.server.go": "package page\n\nfunc HandleRequest() {}\n",
+	}
+	for path, content := range files {
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		_ = os.WriteFile(path, []byte(content), 0644)
+	}
+
+	captureOutput(func() {
+		err := runGenerate(t)
+		if err == nil || !strings.Contains(err.Error(), `rename it to "__page"`) {
+			t.Errorf("expected import error with rename hint, got %v", err)
+		}
+	})
+}
+
+func TestGenerateCommand_CustomOutAndPackage(t *testing.T) {
+	tmp := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	_ = os.Chdir(tmp)
+
+	_ = os.WriteFile("go.mod", []byte("module example.com/site\n"), 0644)
+
+	captureOutput(func() {
+		if err := runGenerate(t, "--out", "handlers/registry.go", "--package", "handlers"); err == nil {
+			t.Error("expected error when output directory is missing")
+		}
+	})
+
+	_ = os.MkdirAll("handlers", 0755)
+	captureOutput(func() {
+		if err := runGenerate(t, "--out", "handlers/registry.go", "--package", "handlers"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	data, _ := os.ReadFile(filepath.Join("handlers", "registry.go"))
+	if !strings.Contains(string(data), "package handlers") {
+		t.Errorf("expected custom package, got:\n%s", data)
+	}
+}
+
+func TestGenerateCommand_ModuleNameFails(t *testing.T) {
+	tmp := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	_ = os.Chdir(tmp)
+
+	if err := runGenerate(t); err == nil || !strings.Contains(err.Error(), "failed to determine module name") {
+		t.Errorf("expected module name error, got %v", err)
+	}
+}
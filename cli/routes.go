@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-barry/barry/core"
+	"github.com/urfave/cli/v2"
+)
+
+var routesOutput io.Writer = os.Stdout
+
+var RoutesCommand = &cli.Command{
+	Name:  "routes",
+	Usage: "List resolved page and API routes in match order",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{Name: "json", Usage: "Print the route table as JSON"},
+	},
+	Action: func(c *cli.Context) error {
+		config := core.LoadConfig("barry.config.yml")
+		table := core.LoadRouteTable(*config)
+
+		if c.Bool("json") {
+			encoder := json.NewEncoder(routesOutput)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(table)
+		}
+
+		printRouteTable(routesOutput, table)
+		return nil
+	},
+}
+
+func printRouteTable(out io.Writer, table []core.RouteInfo) {
+	if len(table) == 0 {
+		fmt.Fprintln(out, "No routes found.")
+		return
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATTERN\tPARAMS\tTEMPLATE\tLAYOUT\tSERVER\tPLUGIN")
+
+	warnings := []string{}
+	for _, route := range table {
+		pattern := route.Pattern
+		if len(route.Methods) > 0 {
+			pattern += " [" + strings.Join(route.Methods, ",") + "]"
+		}
+		if route.Unreachable {
+			pattern = "⚠️ " + pattern
+		}
+
+		plugin := "-"
+		if route.ServerFile != "" {
+			plugin = "no"
+			if route.Plugin {
+				plugin = "yes"
+			}
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			pattern,
+			orDash(strings.Join(route.Params, ",")),
+			orDash(route.Template),
+			orDash(route.Layout),
+			orDash(route.ServerFile),
+			plugin,
+		)
+
+		for _, warning := range route.Warnings {
+			warnings = append(warnings, fmt.Sprintf("⚠️ %s: %s", route.Pattern, warning))
+		}
+	}
+	tw.Flush()
+
+	if len(warnings) > 0 {
+		fmt.Fprintln(out)
+		for _, warning := range warnings {
+			fmt.Fprintln(out, warning)
+		}
+	}
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-barry/barry/core"
+	"github.com/urfave/cli/v2"
+)
+
+func runRoutesCommand(t *testing.T, args ...string) string {
+	t.Helper()
+
+	tmp := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(tmp, "routes", "blog", "_slug"), 0755)
+	_ = os.WriteFile(filepath.Join(tmp, "routes", "blog", "_slug", "index.html"), []byte("post"), 0644)
+	_ = os.MkdirAll(filepath.Join(tmp, "routes", "blog", "_id"), 0755)
+	_ = os.WriteFile(filepath.Join(tmp, "routes", "blog", "_id", "index.html"), []byte("post"), 0644)
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	_ = os.Chdir(tmp)
+
+	var buf bytes.Buffer
+	original := routesOutput
+	routesOutput = &buf
+	defer func() { routesOutput = original }()
+
+	app := &cli.App{Commands: []*cli.Command{RoutesCommand}}
+	if err := app.Run(append([]string{"barry", "routes"}, args...)); err != nil {
+		t.Fatalf("routes command failed: %v", err)
+	}
+	return buf.String()
+}
+
+func TestRoutesCommand_PrintsTable(t *testing.T) {
+	output := runRoutesCommand(t)
+
+	if !strings.Contains(output, "PATTERN") || !strings.Contains(output, "/blog/:id") {
+		t.Errorf("expected table output, got:\n%s", output)
+	}
+	if !strings.Contains(output, "⚠️ /blog/:slug: fully shadowed by routes/blog/_id") {
+		t.Errorf("expected shadowed warning, got:\n%s", output)
+	}
+}
+
+func TestRoutesCommand_JSON(t *testing.T) {
+	output := runRoutesCommand(t, "--json")
+
+	var table []core.RouteInfo
+	if err := json.Unmarshal([]byte(output), &table); err != nil {
+		t.Fatalf("expected valid JSON, got %v:\n%s", err, output)
+	}
+	if len(table) != 2 || table[0].Pattern != "/blog/:id" || !table[1].Unreachable {
+		t.Errorf("unexpected route table: %+v", table)
+	}
+}
+
+func TestPrintRouteTable_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	printRouteTable(&buf, nil)
+	if !strings.Contains(buf.String(), "No routes found.") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+}
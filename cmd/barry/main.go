@@ -20,6 +20,9 @@ func newApp() *clilib.App {
 			barrycli.CheckCommand,
 			barrycli.InfoCommand,
 			barrycli.BuildCommand,
+			barrycli.RoutesCommand,
+			barrycli.GenerateCommand,
+			barrycli.DoctorCommand,
 		},
 	}
 }
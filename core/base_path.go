@@ -0,0 +1,63 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+)
+
+func NormalizeBasePath(basePath string) string {
+	trimmed := strings.Trim(strings.TrimSpace(basePath), "/")
+	if trimmed == "" {
+		return ""
+	}
+	return "/" + trimmed
+}
+
+func withBasePath(basePath, path string) string {
+	if basePath == "" || !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") {
+		return path
+	}
+	if path == basePath || strings.HasPrefix(path, basePath+"/") {
+		return path
+	}
+	return basePath + path
+}
+
+func stripBasePath(basePath, path string) string {
+	if basePath == "" {
+		return path
+	}
+	if path == basePath {
+		return "/"
+	}
+	if strings.HasPrefix(path, basePath+"/") {
+		return strings.TrimPrefix(path, basePath)
+	}
+	return path
+}
+
+func MountBasePath(basePath string, next http.Handler) http.Handler {
+	basePath = NormalizeBasePath(basePath)
+	if basePath == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != basePath && !strings.HasPrefix(req.URL.Path, basePath+"/") {
+			http.NotFound(w, req)
+			return
+		}
+
+		mounted := req.Clone(req.Context())
+		mounted.URL.Path = stripBasePath(basePath, req.URL.Path)
+		mounted.URL.RawPath = ""
+
+		hooked := &headerHookWriter{ResponseWriter: w, before: func(h http.Header) {
+			if location := h.Get("Location"); location != "" {
+				h.Set("Location", withBasePath(basePath, location))
+			}
+		}}
+
+		next.ServeHTTP(hooked, mounted)
+	})
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNormalizeBasePath(t *testing.T) {
+	for input, expected := range map[string]string{
+		"":        "",
+		"/":       "",
+		"docs":    "/docs",
+		"/docs/":  "/docs",
+		" /a/b/ ": "/a/b",
+	} {
+		if got := NormalizeBasePath(input); got != expected {
+			t.Errorf("NormalizeBasePath(%q): expected %q, got %q", input, expected, got)
+		}
+	}
+}
+
+func TestMountBasePath_StripsPrefixAndRewritesLocation(t *testing.T) {
+	var seen string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.URL.Path
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+
+	handler := MountBasePath("docs/", next)
+
+	for path, expected := range map[string]string{
+		"/docs":           "/",
+		"/docs/":          "/",
+		"/docs/api/users": "/api/users",
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || seen != expected {
+			t.Errorf("%s: expected %s, got %s (%d)", path, expected, seen, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/old", nil))
+	if loc := rec.Header().Get("Location"); loc != "/docs/new" {
+		t.Errorf("expected prefixed Location, got %q", loc)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docsearch", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 outside base path, got %d", rec.Code)
+	}
+
+	if MountBasePath("", next) == nil {
+		t.Error("expected handler without base path")
+	}
+}
+
+func TestMountBasePath_KeepsURLForRedirectsPrefixedOnce(t *testing.T) {
+	site := fstest.MapFS{"routes/about/index.html": {Data: []byte("about")}}
+	config := Config{BasePath: "/docs"}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(withURLBuilder(r.Context(), site, config))
+		target, err := URLFor(r, "about", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		http.Redirect(w, r, target, http.StatusFound)
+	})
+
+	rec := httptest.NewRecorder()
+	MountBasePath(config.BasePath, next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/old", nil))
+	if loc := rec.Header().Get("Location"); loc != "/docs/about" {
+		t.Errorf("expected basePath added once, got %q", loc)
+	}
+}
+
+func TestRouter_BasePathInReloadScriptAndCanonical(t *testing.T) {
+	cfg, cleanup := setupRouterTestEnv(t)
+	defer cleanup()
+
+	cfg.BasePath = "/docs/"
+	router := MountBasePath(cfg.BasePath, NewRouter(cfg, RuntimeContext{Env: "dev"}))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/test", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `location.host + "/docs/__barry_reload"`) {
+		t.Errorf("expected prefixed reload socket, got: %s", rec.Body.String())
+	}
+
+	r := &Router{config: Config{BasePath: "/docs"}}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "example.com"
+	if got := r.canonicalURL(req); got != "http://example.com/docs" {
+		t.Errorf("expected canonical root under base path, got %s", got)
+	}
+}
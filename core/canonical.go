@@ -0,0 +1,90 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	TrailingSlashIgnore = "ignore"
+	TrailingSlashAlways = "always"
+	TrailingSlashNever  = "never"
+)
+
+func validTrailingSlash(mode string) bool {
+	switch mode {
+	case "", TrailingSlashIgnore, TrailingSlashAlways, TrailingSlashNever:
+		return true
+	}
+	return false
+}
+
+func canonicalPath(path, trailingSlash string, lowercase bool) string {
+	parts := []string{}
+	for _, part := range strings.Split(path, "/") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return "/"
+	}
+
+	cleaned := "/" + strings.Join(parts, "/")
+	if lowercase {
+		cleaned = strings.ToLower(cleaned)
+	}
+
+	switch trailingSlash {
+	case TrailingSlashAlways:
+		if !strings.Contains(parts[len(parts)-1], ".") {
+			cleaned += "/"
+		}
+	case TrailingSlashNever:
+	default:
+		if strings.HasSuffix(path, "/") {
+			cleaned += "/"
+		}
+	}
+
+	return cleaned
+}
+
+func (r *Router) canonicalRedirect(req *http.Request) (string, bool) {
+	mode := r.config.TrailingSlash
+	if mode != TrailingSlashAlways && mode != TrailingSlashNever && !r.config.LowercasePaths {
+		return "", false
+	}
+
+	canonical := canonicalPath(req.URL.Path, mode, r.config.LowercasePaths)
+	if canonical == req.URL.Path {
+		return "", false
+	}
+	return canonical, true
+}
+
+func (r *Router) canonicalURL(req *http.Request) string {
+	mode := r.config.TrailingSlash
+	if mode != TrailingSlashAlways {
+		mode = TrailingSlashNever
+	}
+
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	if proto := req.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = strings.TrimSpace(strings.Split(proto, ",")[0])
+	}
+
+	path := canonicalPath(req.URL.Path, mode, r.config.LowercasePaths)
+	if r.config.BasePath != "" {
+		if path == "/" && mode != TrailingSlashAlways {
+			path = ""
+		}
+		path = r.config.BasePath + path
+	}
+
+	return fmt.Sprintf("%s://%s%s", scheme, req.Host, path)
+}
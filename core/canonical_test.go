@@ -0,0 +1,99 @@
+package core
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalPath(t *testing.T) {
+	cases := []struct {
+		path      string
+		mode      string
+		lowercase bool
+		expected  string
+	}{
+		{"/", TrailingSlashAlways, false, "/"},
+		{"//", TrailingSlashNever, false, "/"},
+		{"/about", TrailingSlashAlways, false, "/about/"},
+		{"/about/", TrailingSlashNever, false, "/about"},
+		{"//about//team/", TrailingSlashNever, false, "/about/team"},
+		{"/sitemap.xml", TrailingSlashAlways, false, "/sitemap.xml"},
+		{"/About/", TrailingSlashIgnore, true, "/about/"},
+		{"/About", "", true, "/about"},
+	}
+
+	for _, c := range cases {
+		if got := canonicalPath(c.path, c.mode, c.lowercase); got != c.expected {
+			t.Errorf("canonicalPath(%q, %q, %v): expected %q, got %q", c.path, c.mode, c.lowercase, c.expected, got)
+		}
+	}
+}
+
+func TestRouter_CanonicalRedirects(t *testing.T) {
+	cfg, cleanup := setupRouterTestEnv(t)
+	defer cleanup()
+
+	cfg.TrailingSlash = TrailingSlashNever
+	cfg.LowercasePaths = true
+	router := NewRouter(cfg, RuntimeContext{Env: "prod"})
+
+	for path, expected := range map[string]string{
+		"/test/":       "/test",
+		"//test":       "/test",
+		"/Test?page=2": "/test?page=2",
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rec.Code != http.StatusPermanentRedirect {
+			t.Errorf("%s: expected 308, got %d", path, rec.Code)
+		}
+		if loc := rec.Header().Get("Location"); loc != expected {
+			t.Errorf("%s: expected Location %q, got %q", path, expected, loc)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected canonical path to render, got %d", rec.Code)
+	}
+}
+
+func TestRouter_IgnoreModeDoesNotRedirect(t *testing.T) {
+	cfg, cleanup := setupRouterTestEnv(t)
+	defer cleanup()
+
+	router := NewRouter(cfg, RuntimeContext{Env: "prod"})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 without canonicalization, got %d", rec.Code)
+	}
+}
+
+func TestCanonicalURL_ExposedToTemplates(t *testing.T) {
+	cfg, cleanup := setupRouterTestEnv(t)
+	defer cleanup()
+
+	_ = os.WriteFile("routes/test/index.html", []byte(`<!-- layout: layout.html -->
+{{ define "content" }}<link rel="canonical" href="{{ .CanonicalURL }}">{{ end }}`), 0644)
+
+	cfg.TrailingSlash = TrailingSlashAlways
+	router := NewRouter(cfg, RuntimeContext{Env: "prod"})
+
+	req := httptest.NewRequest(http.MethodGet, "/test/", nil)
+	req.Host = "example.com"
+	req.TLS = &tls.ConnectionState{}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if !strings.Contains(rec.Body.String(), `href="https://example.com/test/"`) {
+		t.Errorf("expected canonical URL in output, got: %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,216 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"text/template/parse"
+	"unicode"
+	"unicode/utf8"
+)
+
+const componentHandlerPrefix = "Handle"
+
+func isComponentServerFile(path string) bool {
+	base := filepath.Base(path)
+	if !strings.HasSuffix(base, ".server.go") || strings.HasPrefix(base, "_") {
+		return false
+	}
+	return base != "index.server.go" && base != layoutServerFile
+}
+
+func isComponentHandler(name string) bool {
+	return strings.HasPrefix(name, componentHandlerPrefix) && len(name) > len(componentHandlerPrefix) && !knownHandlers()[name]
+}
+
+func componentHandlerName(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("component name must not be empty")
+	}
+	for i, c := range name {
+		if !unicode.IsLetter(c) && c != '_' && (i == 0 || !unicode.IsDigit(c)) {
+			return "", fmt.Errorf("component %q has server logic but is not a valid Go identifier", name)
+		}
+	}
+	first, size := utf8.DecodeRuneInString(name)
+	return componentHandlerPrefix + string(unicode.ToUpper(first)) + name[size:], nil
+}
+
+func (r *Router) componentServerPath(tmpl *template.Template, name string) string {
+	t := tmpl.Lookup(name)
+	if t == nil || t.Tree == nil {
+		return ""
+	}
+	for _, path := range r.components() {
+		if filepath.Base(path) == t.Tree.ParseName {
+			serverPath := strings.TrimSuffix(path, ".html") + ".server.go"
+			if r.fileExists(serverPath) {
+				return serverPath
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+type pageTemplate struct {
+	tmpl             *template.Template
+	serverComponents bool
+}
+
+func (r *Router) parsePageTemplate(files []string) (*pageTemplate, error) {
+	var parsed *template.Template
+	tmpl := template.New("").Funcs(siteTemplateFuncs(r.fsys, r.env, r.config)).Funcs(template.FuncMap{
+		"component": func(name string, props ...map[string]interface{}) (template.HTML, error) {
+			data, _ := componentProps(props)
+			return executeComponent(parsed, name, data)
+		},
+	})
+
+	parsed, err := parseSiteFiles(r.fsys, tmpl, files...)
+	if err != nil {
+		return nil, err
+	}
+	return &pageTemplate{tmpl: parsed, serverComponents: r.usesServerComponents(parsed)}, nil
+}
+
+func (p *pageTemplate) forRequest(r *Router, req *http.Request) (*template.Template, error) {
+	if !p.serverComponents {
+		return p.tmpl, nil
+	}
+	return r.withComponents(p.tmpl, req)
+}
+
+func (r *Router) usesServerComponents(tmpl *template.Template) bool {
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		found := false
+		walkComponentCalls(t.Tree.Root, func(name string, literal bool) {
+			if !literal || r.componentServerPath(tmpl, name) != "" {
+				found = true
+			}
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+func walkComponentCalls(node parse.Node, visit func(name string, literal bool)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkComponentCalls(child, visit)
+		}
+	case *parse.ActionNode:
+		walkComponentCalls(n.Pipe, visit)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			walkComponentCalls(cmd, visit)
+		}
+	case *parse.CommandNode:
+		if len(n.Args) > 0 {
+			if ident, ok := n.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "component" {
+				name, literal := "", false
+				if len(n.Args) > 1 {
+					if str, ok := n.Args[1].(*parse.StringNode); ok {
+						name, literal = str.Text, true
+					}
+				}
+				visit(name, literal)
+			}
+		}
+		for _, arg := range n.Args {
+			walkComponentCalls(arg, visit)
+		}
+	case *parse.IfNode:
+		walkComponentCalls(&n.BranchNode, visit)
+	case *parse.RangeNode:
+		walkComponentCalls(&n.BranchNode, visit)
+	case *parse.WithNode:
+		walkComponentCalls(&n.BranchNode, visit)
+	case *parse.BranchNode:
+		walkComponentCalls(n.Pipe, visit)
+		walkComponentCalls(n.List, visit)
+		walkComponentCalls(n.ElseList, visit)
+	case *parse.TemplateNode:
+		walkComponentCalls(n.Pipe, visit)
+	}
+}
+
+func componentProps(props []map[string]interface{}) (map[string]interface{}, map[string]string) {
+	data := map[string]interface{}{}
+	params := map[string]string{}
+	for _, p := range props {
+		for k, v := range p {
+			data[k] = v
+			params[k] = fmt.Sprint(v)
+		}
+	}
+	return data, params
+}
+
+func executeComponent(tmpl *template.Template, name string, data map[string]interface{}) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", err
+	}
+	return template.HTML(buf.String()), nil
+}
+
+func (r *Router) withComponents(tmpl *template.Template, req *http.Request) (*template.Template, error) {
+	clone, err := tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	memo := map[string]map[string]interface{}{}
+	clone.Funcs(template.FuncMap{
+		"component": func(name string, props ...map[string]interface{}) (template.HTML, error) {
+			data, params := componentProps(props)
+
+			if serverPath := r.componentServerPath(clone, name); serverPath != "" {
+				key := name + "?" + paramsKey(params)
+				result, ok := memo[key]
+				if !ok {
+					handler, err := componentHandlerName(name)
+					if err != nil {
+						return "", err
+					}
+					result, err = ExecuteServerFile(serverPath, withHandlerName(req, handler), params)
+					if err != nil {
+						return "", fmt.Errorf("component %s: %w", name, err)
+					}
+					memo[key] = result
+				}
+				for k, v := range result {
+					data[k] = v
+				}
+			}
+
+			return executeComponent(clone, name, data)
+		},
+	})
+	return clone, nil
+}
+
+func paramsKey(params map[string]string) string {
+	values := url.Values{}
+	for k, v := range params {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func setupComponentSite(t *testing.T, page string) *[]string {
+	t.Helper()
+	return stubSite(t, map[string]string{
+		"components/widgets/latest.html":      `{{ define "LatestPosts" }}<ul>{{ range .Posts }}<li>{{ . }}</li>{{ end }}</ul>{{ end }}`,
+		"components/widgets/latest.server.go": "package widgets",
+		"components/atoms/badge.html":         `{{ define "Badge" }}<b>{{ .Text }}</b>{{ end }}`,
+		"routes/home/index.html":              "<!-- layout: components/layouts/base.html -->\n{{ define \"content\" }}" + page + "{{ end }}",
+	}, func(_ string, _ *http.Request, params map[string]string) (map[string]interface{}, error) {
+		if params["limit"] == "0" {
+			return nil, errors.New("no posts")
+		}
+		posts := []string{"a", "b", "c"}
+		return map[string]interface{}{"Posts": posts[:len(params["limit"])]}, nil
+	})
+}
+
+func TestServeHTTP_ComponentServerLogicIsMemoized(t *testing.T) {
+	calls := setupComponentSite(t, `{{ component "LatestPosts" (props "limit" 5) }}|{{ component "LatestPosts" (props "limit" 5) }}|{{ component "LatestPosts" (props "limit" 10) }}|{{ component "Badge" (props "Text" "new") }}`)
+	router := NewRouter(Config{OutputDir: "cache"}, RuntimeContext{Env: "prod"})
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home", nil))
+
+		want := "<ul><li>a</li></ul>|<ul><li>a</li></ul>|<ul><li>a</li><li>b</li></ul>|<b>new</b>"
+		if body := rec.Body.String(); body != want {
+			t.Fatalf("expected %q, got %q", want, body)
+		}
+	}
+
+	want := []string{
+		"components/widgets/latest.server.go:HandleLatestPosts?limit=5",
+		"components/widgets/latest.server.go:HandleLatestPosts?limit=10",
+	}
+	if len(*calls) != 4 || (*calls)[0] != want[0] || (*calls)[1] != want[1] || (*calls)[2] != want[0] {
+		t.Errorf("expected one call per distinct props per request, got %v", *calls)
+	}
+}
+
+func TestServeHTTP_ClonesOnlyForServerComponents(t *testing.T) {
+	cases := map[string]bool{
+		`{{ component "Badge" (props "Text" "new") }}`:                          false,
+		`{{ if true }}{{ component "LatestPosts" (props "limit" 5) }}{{ end }}`: true,
+		`{{ with "Badge" }}{{ component . (props "Text" "new") }}{{ end }}`:     true,
+	}
+
+	for page, clones := range cases {
+		setupComponentSite(t, page)
+		router := NewRouter(Config{OutputDir: "cache"}, RuntimeContext{Env: "prod"}).(*Router)
+
+		for i := 0; i < 2; i++ {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s: expected 200, got %d %q", page, rec.Code, rec.Body.String())
+			}
+		}
+
+		router.templateCache.Range(func(_, val interface{}) bool {
+			if got := val.(*pageTemplate).serverComponents; got != clones {
+				t.Errorf("%s: expected serverComponents %v, got %v", page, clones, got)
+			}
+			return true
+		})
+	}
+}
+
+func TestServeHTTP_ComponentErrorFailsRender(t *testing.T) {
+	setupComponentSite(t, `{{ component "LatestPosts" (props "limit" 0) }}`)
+	router := NewRouter(Config{OutputDir: "cache"}, RuntimeContext{Env: "prod"})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home", nil))
+
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "component LatestPosts: no posts") {
+		t.Errorf("expected component error, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestServeHTTP_UnknownComponent(t *testing.T) {
+	setupComponentSite(t, `{{ component "Missing" }}`)
+	router := NewRouter(Config{OutputDir: "cache"}, RuntimeContext{Env: "prod"})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home", nil))
+
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), `"Missing" is undefined`) {
+		t.Errorf("expected missing component error, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestComponentHandlerName(t *testing.T) {
+	cases := map[string]string{
+		"LatestPosts": "HandleLatestPosts",
+		"card":        "HandleCard",
+		"ümlaut2":     "HandleÜmlaut2",
+	}
+	for name, want := range cases {
+		if got, err := componentHandlerName(name); err != nil || got != want {
+			t.Errorf("%s: expected %s, got %s (%v)", name, want, got, err)
+		}
+	}
+
+	for _, name := range []string{"", "latest-posts", "2col"} {
+		if _, err := componentHandlerName(name); err == nil {
+			t.Errorf("expected %q to be rejected", name)
+		}
+	}
+}
+
+func TestIsComponentServerFile(t *testing.T) {
+	cases := map[string]bool{
+		"components/widgets/latest.server.go": true,
+		"components/layouts/layout.server.go": false,
+		"routes/index.server.go":              false,
+		"routes/_middleware.server.go":        false,
+		"api/users/index.go":                  false,
+	}
+	for path, want := range cases {
+		if got := isComponentServerFile(path); got != want {
+			t.Errorf("%s: expected %v, got %v", path, want, got)
+		}
+	}
+}
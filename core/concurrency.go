@@ -0,0 +1,54 @@
+package core
+
+import (
+	"net/http"
+	"regexp"
+)
+
+type concurrencyLimit struct {
+	pattern *regexp.Regexp
+	sem     chan struct{}
+}
+
+func (r *Router) setConcurrency(rules []ConcurrencyRule) {
+	limits := []concurrencyLimit{}
+	for _, rule := range rules {
+		if rule.MaxConcurrency <= 0 {
+			r.logf("⚠️ Skipping concurrency %s: maxConcurrency must be at least 1\n", rule.Source)
+			continue
+		}
+		pattern, err := compilePattern(rule.Source)
+		if err != nil {
+			r.logf("⚠️ Skipping concurrency %s: %v\n", rule.Source, err)
+			continue
+		}
+		limits = append(limits, concurrencyLimit{pattern: pattern, sem: make(chan struct{}, rule.MaxConcurrency)})
+	}
+
+	r.routesMu.Lock()
+	r.limits = limits
+	r.routesMu.Unlock()
+}
+
+func (r *Router) acquireRoute(req *http.Request, path string) (func(), error) {
+	r.routesMu.RLock()
+	var sem chan struct{}
+	for _, limit := range r.limits {
+		if limit.pattern.MatchString(path) {
+			sem = limit.sem
+			break
+		}
+	}
+	r.routesMu.RUnlock()
+
+	if sem == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+}
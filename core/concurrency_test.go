@@ -0,0 +1,128 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func serveConcurrently(router http.Handler, path string, n int) []int {
+	codes := make([]int, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			codes[i] = rec.Code
+		}(i)
+	}
+	wg.Wait()
+	return codes
+}
+
+func TestServeHTTP_ExecutesSameRouteInParallel(t *testing.T) {
+
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	stubSite(t, nil, func(string, *http.Request, map[string]string) (map[string]interface{}, error) {
+		arrived.Done()
+		done := make(chan struct{})
+		go func() {
+			arrived.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil, nil
+		case <-time.After(2 * time.Second):
+			return nil, HTTPError(http.StatusInternalServerError, "requests were serialized")
+		}
+	})
+
+	router := NewRouter(Config{OutputDir: "cache"}, RuntimeContext{Env: "prod"})
+	for _, code := range serveConcurrently(router, "/slow", 2) {
+		if code != http.StatusOK {
+			t.Errorf("expected both requests to run concurrently, got %d", code)
+		}
+	}
+}
+
+func TestServeHTTP_MaxConcurrencyLimitsRoute(t *testing.T) {
+
+	var current, peak int32
+	stubSite(t, nil, func(string, *http.Request, map[string]string) (map[string]interface{}, error) {
+		n := atomic.AddInt32(&current, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&current, -1)
+		return nil, nil
+	})
+
+	router := NewRouter(Config{
+		OutputDir:   "cache",
+		Concurrency: []ConcurrencyRule{{Source: "/slow", MaxConcurrency: 1}},
+	}, RuntimeContext{Env: "prod"})
+
+	for _, code := range serveConcurrently(router, "/slow", 4) {
+		if code != http.StatusOK {
+			t.Errorf("expected 200, got %d", code)
+		}
+	}
+	if peak != 1 {
+		t.Errorf("expected at most 1 concurrent execution, got %d", peak)
+	}
+}
+
+func TestServeHTTP_MaxConcurrencyWaitHonorsTimeout(t *testing.T) {
+
+	release := make(chan struct{})
+	stubSite(t, nil, func(string, *http.Request, map[string]string) (map[string]interface{}, error) {
+		<-release
+		return nil, nil
+	})
+
+	router := NewRouter(Config{
+		OutputDir:   "cache",
+		Timeout:     "50ms",
+		Concurrency: []ConcurrencyRule{{Source: "/slow", MaxConcurrency: 1}},
+	}, RuntimeContext{Env: "prod"})
+
+	first := make(chan struct{})
+	go func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+		close(first)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	close(release)
+	<-first
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected queued request to time out with 504, got %d", rec.Code)
+	}
+}
+
+func TestSetConcurrency_SkipsInvalidRules(t *testing.T) {
+	r := &Router{}
+	r.setConcurrency([]ConcurrencyRule{
+		{Source: "/ok", MaxConcurrency: 2},
+		{Source: "/zero", MaxConcurrency: 0},
+		{Source: "/bad/_...rest/more", MaxConcurrency: 1},
+	})
+
+	if len(r.limits) != 1 || cap(r.limits[0].sem) != 2 {
+		t.Errorf("expected one limit of 2, got %+v", r.limits)
+	}
+}
@@ -1,20 +1,53 @@
 package core
 
 import (
-	"os"
-
 	"gopkg.in/yaml.v3"
+	"io/fs"
 )
 
 type Config struct {
-	OutputDir    string `yaml:"outputDir"`
-	CacheEnabled bool   `yaml:"cache"`
-	DebugHeaders bool   `yaml:"debugHeaders"`
-	DebugLogs    bool   `yaml:"debugLogs"`
+	OutputDir      string            `yaml:"outputDir"`
+	CacheEnabled   bool              `yaml:"cache"`
+	DebugHeaders   bool              `yaml:"debugHeaders"`
+	DebugLogs      bool              `yaml:"debugLogs"`
+	BasePath       string            `yaml:"basePath"`
+	TrailingSlash  string            `yaml:"trailingSlash"`
+	LowercasePaths bool              `yaml:"lowercasePaths"`
+	Redirects      []RedirectRule    `yaml:"redirects"`
+	Rewrites       []RewriteRule     `yaml:"rewrites"`
+	Timeout        string            `yaml:"timeout"`
+	Timeouts       []TimeoutRule     `yaml:"timeouts"`
+	Concurrency    []ConcurrencyRule `yaml:"concurrency"`
+	LayoutKey      string            `yaml:"layoutKey"`
+}
+
+type RedirectRule struct {
+	Source      string `yaml:"source"`
+	Destination string `yaml:"destination"`
+	Status      int    `yaml:"status"`
+}
+
+type RewriteRule struct {
+	Source      string `yaml:"source"`
+	Destination string `yaml:"destination"`
+}
+
+type TimeoutRule struct {
+	Source  string `yaml:"source"`
+	Timeout string `yaml:"timeout"`
+}
+
+type ConcurrencyRule struct {
+	Source         string `yaml:"source"`
+	MaxConcurrency int    `yaml:"maxConcurrency"`
 }
 
 var LoadConfig = func(path string) *Config {
-	data, err := os.ReadFile(path)
+	return LoadConfigFS(nil, path)
+}
+
+func LoadConfigFS(fsys fs.FS, path string) *Config {
+	data, err := siteReadFile(fsys, path)
 	if err != nil {
 		return &Config{
 			OutputDir:    "./cache",
@@ -30,6 +63,7 @@ var LoadConfig = func(path string) *Config {
 	if cfg.OutputDir == "" {
 		cfg.OutputDir = "./cache"
 	}
+	cfg.BasePath = NormalizeBasePath(cfg.BasePath)
 
 	return &cfg
 }
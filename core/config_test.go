@@ -77,3 +77,54 @@ debugLogs: true
 		t.Error("expected true values for all booleans")
 	}
 }
+
+func TestLoadConfigParsesRedirectsAndRewrites(t *testing.T) {
+	tmp := t.TempDir()
+
+	configYAML := `
+redirects:
+  - source: /old-blog/_slug
+    destination: /blog/_slug
+    status: 301
+rewrites:
+  - source: /docs/_...rest
+    destination: /help/_...rest
+`
+	configPath := filepath.Join(tmp, "barry.config.yml")
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg := LoadConfig(configPath)
+
+	if len(cfg.Redirects) != 1 || cfg.Redirects[0].Source != "/old-blog/_slug" || cfg.Redirects[0].Status != 301 {
+		t.Errorf("unexpected redirects: %+v", cfg.Redirects)
+	}
+	if len(cfg.Rewrites) != 1 || cfg.Rewrites[0].Destination != "/help/_...rest" {
+		t.Errorf("unexpected rewrites: %+v", cfg.Rewrites)
+	}
+}
+
+func TestLoadConfigParsesTimeouts(t *testing.T) {
+	tmp := t.TempDir()
+
+	configYAML := `
+timeout: 10s
+timeouts:
+  - source: /reports/_id
+    timeout: 2m
+`
+	configPath := filepath.Join(tmp, "barry.config.yml")
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg := LoadConfig(configPath)
+
+	if cfg.Timeout != "10s" {
+		t.Errorf("expected timeout 10s, got %q", cfg.Timeout)
+	}
+	if len(cfg.Timeouts) != 1 || cfg.Timeouts[0].Source != "/reports/_id" || cfg.Timeouts[0].Timeout != "2m" {
+		t.Errorf("unexpected timeouts: %+v", cfg.Timeouts)
+	}
+}
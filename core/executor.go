@@ -2,24 +2,19 @@ package core
 
 import (
 	"bytes"
-	"crypto/sha256"
 	"errors"
 	"fmt"
 	"go/format"
 	"io"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"text/template"
-	"time"
 
 	json "github.com/segmentio/encoding/json"
 )
 
-var nowFunc = time.Now
 var formatSource = format.Source
 var barryTmpDir = ".barry-tmp"
 var errorNotFoundMsg = "barry-error: barry: not found"
@@ -39,89 +34,220 @@ var runnerTemplate = defaultRunnerTemplate
 var defaultRunnerTemplate = `package main
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
-	"strings"
 	"os"
+	"reflect"
+	{{- if .ImportPath }}
+
 	target "{{ .ImportPath }}"
+	{{- end }}
 )
 
+type request struct {
+	Handler       string              ` + "`json:\"handler\"`" + `
+	Method        string              ` + "`json:\"method\"`" + `
+	URL           string              ` + "`json:\"url\"`" + `
+	RequestURI    string              ` + "`json:\"requestURI\"`" + `
+	Proto         string              ` + "`json:\"proto\"`" + `
+	Header        map[string][]string ` + "`json:\"header\"`" + `
+	Host          string              ` + "`json:\"host\"`" + `
+	RemoteAddr    string              ` + "`json:\"remoteAddr\"`" + `
+	TLS           bool                ` + "`json:\"tls\"`" + `
+	ContentLength int64               ` + "`json:\"contentLength\"`" + `
+	Body          []byte              ` + "`json:\"body\"`" + `
+	Params        map[string]string   ` + "`json:\"params\"`" + `
+}
+
+type response struct {
+	Result   interface{} ` + "`json:\"result\"`" + `
+	Error    string      ` + "`json:\"error,omitempty\"`" + `
+	NotFound bool        ` + "`json:\"notFound,omitempty\"`" + `
+	Status   int         ` + "`json:\"status,omitempty\"`" + `
+	Location string      ` + "`json:\"location,omitempty\"`" + `
+	Meta     interface{} ` + "`json:\"meta,omitempty\"`" + `
+}
+
+type statusError interface {
+	StatusCode() int
+	RedirectLocation() string
+}
+
+type resultMapper interface {
+	Map() map[string]interface{}
+}
+
+var handlers = map[string]func(*http.Request, map[string]string) (interface{}, error){
+	{{- range .Handlers }}
+	"{{ . }}": func(r *http.Request, p map[string]string) (interface{}, error) {
+		return {{ if $.ImportPath }}target.{{ end }}{{ . }}(r, p)
+	},
+	{{- end }}
+}
+
 func main() {
 	log.SetOutput(os.Stderr)
 
-	body := strings.NewReader({{ .Body | jsonMarshal }})
-	r, _ := http.NewRequest("{{ .Method }}", "{{ .URL }}", body)
+	out := json.NewEncoder(os.Stdout)
+	os.Stdout = os.Stderr
+
+	in := bufio.NewReader(os.Stdin)
+	for {
+		line, err := in.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return
+		}
+
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			log.Println("barry-error: invalid request:", err)
+			os.Exit(1)
+		}
 
-	for key, vals := range map[string][]string{
-		{{- range $k, $v := .Headers }}
-		"{{ $k }}": {{ $v | jsonMarshal }},
-		{{- end }}
-	} {
-		for _, v := range vals {
-			r.Header.Add(key, v)
+		if err := out.Encode(handle(req)); err != nil {
+			log.Println("barry-error: could not encode result:", err)
+			os.Exit(1)
 		}
 	}
+}
 
-	r.Host = "{{ .Host }}"
-	r.RemoteAddr = "{{ .RemoteAddr }}"
+func handle(req request) (resp response) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Println("barry-error: panic:", p)
+			resp = response{Error: fmt.Sprint("panic: ", p)}
+		}
+	}()
 
-	if err := r.ParseForm(); err != nil {
-		log.Println("barry-error: failed to parse form:", err)
-		os.Exit(1)
+	handler, ok := handlers[req.Handler]
+	if !ok {
+		return response{Error: "handler " + req.Handler + " is not defined"}
 	}
 
-	params := map[string]string{
-		{{- range $k, $v := .Params }}
-		"{{ $k }}": "{{ $v }}",
-		{{- end }}
+	r, err := newRequest(req)
+	if err != nil {
+		return response{Error: err.Error()}
 	}
 
-	result, err := target.HandleRequest(r, params)
+	result, err := handler(r, req.Params)
 	if err != nil {
+		var se statusError
+		if errors.As(err, &se) && se.StatusCode() != 0 {
+			return response{Error: err.Error(), Status: se.StatusCode(), Location: se.RedirectLocation()}
+		}
 		log.Println("barry-error:", err)
-		os.Exit(1)
+		return response{Error: err.Error(), NotFound: err.Error() == "barry: not found"}
 	}
 
-	json.NewEncoder(os.Stdout).Encode(result)
+	data, err := resultValue(result)
+	if err != nil {
+		log.Println("barry-error:", err)
+		return response{Error: err.Error()}
+	}
+	return response{Result: data, Meta: resultMeta(result)}
 }
-`
 
-var templateFuncs = template.FuncMap{
-	"jsonMarshal": func(v interface{}) string {
-		switch val := v.(type) {
-		case string:
-			return strconv.Quote(val)
-		case []string:
-			var b strings.Builder
-			b.WriteString("[]string{")
-			for i, s := range val {
-				if i > 0 {
-					b.WriteString(", ")
-				}
-				b.WriteString(strconv.Quote(s))
-			}
-			b.WriteString("}")
-			return b.String()
-		default:
-			b, _ := json.Marshal(v)
-			return string(b)
+func resultMeta(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	method := reflect.ValueOf(v).MethodByName("ResponseMeta")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return nil
+	}
+	return method.Call(nil)[0].Interface()
+}
+
+func resultValue(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case resultMapper:
+		return val.Map(), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
 		}
-	},
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		return v, nil
+	case reflect.Struct:
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("unsupported handler result type %T", v)
 }
 
+func newRequest(req request) (*http.Request, error) {
+	var body io.Reader = http.NoBody
+	if len(req.Body) > 0 {
+		body = bytes.NewReader(req.Body)
+	}
+
+	r, err := http.NewRequest(req.Method, req.URL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Header = http.Header{}
+	for key, vals := range req.Header {
+		r.Header[key] = append([]string(nil), vals...)
+	}
+
+	r.RequestURI = req.RequestURI
+	r.Host = req.Host
+	r.RemoteAddr = req.RemoteAddr
+	r.ContentLength = req.ContentLength
+	if req.ContentLength < 0 || len(req.Body) > 0 {
+		r.ContentLength = int64(len(req.Body))
+	}
+
+	if major, minor, ok := http.ParseHTTPVersion(req.Proto); ok {
+		r.Proto, r.ProtoMajor, r.ProtoMinor = req.Proto, major, minor
+	}
+	if req.TLS {
+		r.TLS = &tls.ConnectionState{}
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("failed to parse form: %w", err)
+	}
+	if len(req.Body) > 0 {
+		r.Body = io.NopCloser(bytes.NewReader(req.Body))
+	}
+
+	return r, nil
+}
+`
+
 type ExecContext struct {
 	ImportPath string
-	Params     map[string]string
-	Method     string
-	URL        string
-	Headers    map[string][]string
-	Body       string
-	Host       string
-	RemoteAddr string
+	Handlers   []string
 }
 
 var ExecuteServerFile = func(filePath string, req *http.Request, params map[string]string) (map[string]interface{}, error) {
+	if handler, ok := registeredHandler(filePath, req); ok {
+		return callWithContext(req, func() (map[string]interface{}, error) {
+			return handler.call(req, params)
+		})
+	}
+
 	result, err := LoadPluginAndCallFunc(filePath, req, params)
 	if err != nil && err != ErrPluginNotFound {
 		return nil, err
@@ -134,6 +260,16 @@ var ExecuteServerFile = func(filePath string, req *http.Request, params map[stri
 }
 
 var ExecuteAPIFile = func(filePath string, req *http.Request, params map[string]string) ([]byte, error) {
+	if handler, ok := registeredHandler(filePath, req); ok {
+		result, err := callWithContext(req, func() (map[string]interface{}, error) {
+			return handler.call(req, params)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(result)
+	}
+
 	result, err := LoadPluginAndCallFunc(filePath, req, params)
 	if err != nil && err != ErrPluginNotFound {
 		return nil, err
@@ -163,21 +299,94 @@ func ExecuteServerFileWithSubprocess(filePath string, req *http.Request, params
 	bodyBytes, _ := io.ReadAll(req.Body)
 	req.Body = io.NopCloser(bytes.NewReader(bodyBytes))
 
-	ctx := ExecContext{
-		ImportPath: importPath,
-		Params:     params,
-		Method:     req.Method,
-		URL:        req.URL.String(),
-		Headers:    req.Header,
-		Body:       string(bodyBytes),
-		Host:       req.Host,
-		RemoteAddr: req.RemoteAddr,
+	w := workersFor(req).poolFor(absPath)
+	if err := w.acquire(req.Context()); err != nil {
+		return nil, err
+	}
+
+	if w.stale() {
+		w.retire()
+		if err := buildWorker(w, absPath, modRoot, importPath); err != nil {
+			w.release()
+			return nil, err
+		}
+	}
+
+	proc, err := w.take(modRoot)
+	w.release()
+	if err != nil {
+		return nil, err
+	}
+	defer w.put(proc)
+
+	resp, err := proc.call(req.Context(), workerRequest{
+		Handler:       HandlerName(req),
+		Method:        req.Method,
+		URL:           req.URL.String(),
+		RequestURI:    req.RequestURI,
+		Proto:         req.Proto,
+		Header:        req.Header,
+		Host:          req.Host,
+		RemoteAddr:    req.RemoteAddr,
+		TLS:           req.TLS != nil,
+		ContentLength: req.ContentLength,
+		Body:          bodyBytes,
+		Params:        params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.NotFound {
+		return nil, ErrNotFound
+	}
+	if resp.Status != 0 {
+		if resp.Location != "" {
+			return nil, &ResponseError{Status: resp.Status, Location: resp.Location}
+		}
+		return nil, &ResponseError{Status: resp.Status, Message: resp.Error}
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+
+	result, err := decodeResult(resp.Result)
+	if err != nil {
+		return nil, fmt.Errorf("json decode error: %w", err)
+	}
+	if resp.Meta != nil {
+		recordResponseMeta(req, *resp.Meta)
+	}
+
+	return result, nil
+}
+
+func buildWorker(w *workerPool, absPath, modRoot, importPath string) error {
+	ctx := ExecContext{ImportPath: importPath}
+
+	var inlineSource []byte
+	var err error
+	if strings.HasPrefix(filepath.Base(absPath), "_") || CheckImportable(filepath.Dir(absPath)) != nil {
+		inlineSource, err = MainPackageSource(absPath)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", absPath, err)
+		}
+		ctx.ImportPath = ""
+		known := knownHandlers()
+		known[middlewareHandlerName] = true
+		ctx.Handlers, err = detectFuncs(nil, []string{absPath}, func(name string) bool { return known[name] })
+	} else if isComponentServerFile(absPath) {
+		ctx.Handlers, err = DetectComponentHandlers(filepath.Dir(absPath))
+	} else {
+		ctx.Handlers, err = DetectHandlers(filepath.Dir(absPath))
+	}
+	if err != nil {
+		return fmt.Errorf("exec error: %w", err)
 	}
 
 	var buf bytes.Buffer
-	tmpl := template.Must(template.New("runner").Funcs(templateFuncs).Parse(runnerTemplate))
+	tmpl := template.Must(template.New("runner").Parse(runnerTemplate))
 	if err := tmpl.Execute(&buf, ctx); err != nil {
-		return nil, fmt.Errorf("template execution error: %w", err)
+		return fmt.Errorf("template execution error: %w", err)
 	}
 
 	formatted, err := formatSource(buf.Bytes())
@@ -185,42 +394,32 @@ func ExecuteServerFileWithSubprocess(filePath string, req *http.Request, params
 		formatted = buf.Bytes()
 	}
 
-	tmpRoot := filepath.Join(modRoot, barryTmpDir)
-	hash := sha256.Sum256([]byte(absPath + nowFunc().String()))
-	runDir := filepath.Join(tmpRoot, fmt.Sprintf("%x", hash[:8]))
+	runDir := workerRunDir(modRoot, absPath, w.setID)
 	if err := osMkdirAll(runDir, os.ModePerm); err != nil {
-		return nil, fmt.Errorf("could not create temp dir: %w", err)
+		return fmt.Errorf("could not create temp dir: %w", err)
 	}
 
 	tmpFile := filepath.Join(runDir, "main.go")
 	if err := osWriteFile(tmpFile, formatted, 0644); err != nil {
-		return nil, fmt.Errorf("could not write temp file: %w", err)
+		return fmt.Errorf("could not write temp file: %w", err)
 	}
 
-	cmd := exec.Command("go", "run", tmpFile)
-	cmd.Dir = modRoot
-
-	var outBuf, errBuf bytes.Buffer
-	cmd.Stdout = &outBuf
-	cmd.Stderr = io.MultiWriter(os.Stderr, &errBuf)
-
-	err = cmd.Run()
-	_ = os.RemoveAll(runDir)
-
-	if err != nil {
-		errText := errBuf.String()
-		if strings.Contains(errText, errorNotFoundMsg) {
-			return nil, ErrNotFound
+	files := []string{tmpFile}
+	if inlineSource != nil {
+		inlineFile := filepath.Join(runDir, "handler.go")
+		if err := osWriteFile(inlineFile, inlineSource, 0644); err != nil {
+			return fmt.Errorf("could not write temp file: %w", err)
 		}
-		return nil, fmt.Errorf("exec error: %v\nstderr: %s", err, errText)
+		files = append(files, inlineFile)
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(outBuf.Bytes(), &result); err != nil {
-		return nil, fmt.Errorf("json decode error: %w", err)
+	if err := w.build(modRoot, runDir, files); err != nil {
+		return err
 	}
-
-	return result, nil
+	if inlineSource != nil {
+		w.stamps[absPath] = modTime(absPath)
+	}
+	return nil
 }
 
 func ExecuteAPIFileWithSubprocess(filePath string, req *http.Request, params map[string]string) ([]byte, error) {
@@ -6,7 +6,6 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"testing"
 )
@@ -43,29 +42,6 @@ func TestFindGoModRoot(t *testing.T) {
 	}
 }
 
-func Test_jsonMarshalFunc_string_and_slice(t *testing.T) {
-	jsonFunc, ok := templateFuncs["jsonMarshal"].(func(interface{}) string)
-	if !ok {
-		t.Fatal("jsonMarshal function not found or has wrong type")
-	}
-
-	str := jsonFunc("hello")
-	if str != strconv.Quote("hello") {
-		t.Errorf("expected quoted string, got %s", str)
-	}
-
-	slice := jsonFunc([]string{"a", "b"})
-	expected := `[]string{"a", "b"}`
-	if slice != expected {
-		t.Errorf("expected %s, got %s", expected, slice)
-	}
-
-	obj := jsonFunc(map[string]int{"count": 42})
-	if !strings.Contains(obj, `"count":42`) {
-		t.Errorf("expected JSON string, got %s", obj)
-	}
-}
-
 func TestExecuteAPIFileWithSubprocess_JSONEncode(t *testing.T) {
 	original := ExecuteServerFileWithSubprocessFunc
 	defer func() { ExecuteServerFileWithSubprocessFunc = original }()
@@ -527,3 +503,115 @@ func TestFindGoModRoot_ReadFails(t *testing.T) {
 		t.Errorf("expected read failure error, got %v", err)
 	}
 }
+
+func TestExecuteServerFileWithSubprocess_CallsMethodHandler(t *testing.T) {
+	originalMod := findGoModRoot
+	defer func() { findGoModRoot = originalMod }()
+	runnerTemplate = defaultRunnerTemplate
+
+	tmp := t.TempDir()
+	_ = os.WriteFile(filepath.Join(tmp, "go.mod"), []byte("module example.com/methods\n"), 0644)
+
+	dir := filepath.Join(tmp, "api", "users")
+	_ = os.MkdirAll(dir, 0755)
+
+	goFile := filepath.Join(dir, "post.go")
+	code := `
+package users
+
+import "net/http"
+
+func HandlePost(r *http.Request, _ map[string]string) (map[string]interface{}, error) {
+	return map[string]interface{}{"method": r.Method}, nil
+}
+`
+	_ = os.WriteFile(goFile, []byte(code), 0644)
+
+	findGoModRoot = func(startPath string) (string, string, error) {
+		return tmp, "example.com/methods", nil
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/users", nil)
+	result, err := ExecuteServerFileWithSubprocess(goFile, withHandlerName(req, "HandlePost"), nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["method"] != "POST" {
+		t.Errorf("expected HandlePost to run, got %v", result)
+	}
+}
+
+func TestExecuteServerFileWithSubprocess_InlinesUnderscoreFiles(t *testing.T) {
+	originalMod := findGoModRoot
+	defer func() { findGoModRoot = originalMod }()
+	runnerTemplate = defaultRunnerTemplate
+
+	tmp := t.TempDir()
+	_ = os.WriteFile(filepath.Join(tmp, "go.mod"), []byte("module example.com/middleware\n"), 0644)
+
+	dir := filepath.Join(tmp, "routes", "admin")
+	_ = os.MkdirAll(dir, 0755)
+
+	goFile := filepath.Join(dir, "_middleware.server.go")
+	code := `package admin
+
+import "net/http"
+
+func Middleware(r *http.Request, _ map[string]string) (map[string]interface{}, error) {
+	return map[string]interface{}{"User": "ada"}, nil
+}
+`
+	_ = os.WriteFile(goFile, []byte(code), 0644)
+
+	findGoModRoot = func(startPath string) (string, string, error) {
+		return tmp, "example.com/middleware", nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	result, err := ExecuteServerFileWithSubprocess(goFile, withHandlerName(req, "Middleware"), nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["User"] != "ada" {
+		t.Errorf("expected middleware result, got %v", result)
+	}
+}
+
+func TestExecuteServerFileWithSubprocess_InlinesBracketSegmentFiles(t *testing.T) {
+	originalMod := findGoModRoot
+	defer func() { findGoModRoot = originalMod }()
+	runnerTemplate = defaultRunnerTemplate
+
+	tmp := t.TempDir()
+	_ = os.WriteFile(filepath.Join(tmp, "go.mod"), []byte("module example.com/brackets\n"), 0644)
+
+	dir := filepath.Join(tmp, "routes", "list", "_[page]")
+	_ = os.MkdirAll(dir, 0755)
+
+	goFile := filepath.Join(dir, "index.server.go")
+	code := `package page
+
+import "net/http"
+
+func HandleRequest(r *http.Request, p map[string]string) (map[string]interface{}, error) {
+	return map[string]interface{}{"page": p["page"]}, nil
+}
+`
+	_ = os.WriteFile(goFile, []byte(code), 0644)
+
+	findGoModRoot = func(startPath string) (string, string, error) {
+		return tmp, "example.com/brackets", nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/list/2", nil)
+	result, err := ExecuteServerFileWithSubprocess(goFile, req, map[string]string{"page": "2"})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["page"] != "2" {
+		t.Errorf("expected handler result, got %v", result)
+	}
+}
@@ -0,0 +1,175 @@
+package core
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const defaultHandlerName = "HandleRequest"
+
+type handlerNameKey struct{}
+
+var methodHandlers = []struct {
+	Method  string
+	Handler string
+}{
+	{http.MethodGet, "HandleGet"},
+	{http.MethodHead, "HandleHead"},
+	{http.MethodPost, "HandlePost"},
+	{http.MethodPut, "HandlePut"},
+	{http.MethodPatch, "HandlePatch"},
+	{http.MethodDelete, "HandleDelete"},
+	{http.MethodOptions, "HandleOptions"},
+}
+
+func DetectHandlers(dir string) ([]string, error) {
+	return detectHandlers(nil, dir)
+}
+
+func detectHandlers(fsys fs.FS, dir string) ([]string, error) {
+	paths, err := siteHandlerSources(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	known := knownHandlers()
+	return detectFuncs(fsys, paths, func(name string) bool { return known[name] })
+}
+
+func DetectComponentHandlers(dir string) ([]string, error) {
+	paths, err := handlerSources(dir)
+	if err != nil {
+		return nil, err
+	}
+	return detectFuncs(nil, paths, isComponentHandler)
+}
+
+func handlerSources(dir string) ([]string, error) {
+	return siteHandlerSources(nil, dir)
+}
+
+func siteHandlerSources(fsys fs.FS, dir string) ([]string, error) {
+	entries, err := siteReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".") {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, name))
+	}
+	return paths, nil
+}
+
+func knownHandlers() map[string]bool {
+	known := map[string]bool{defaultHandlerName: true}
+	for _, mh := range methodHandlers {
+		known[mh.Handler] = true
+	}
+	return known
+}
+
+func detectFuncs(fsys fs.FS, paths []string, match func(string) bool) ([]string, error) {
+	found := map[string]bool{}
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		src, err := siteReadFile(fsys, path)
+		if err != nil {
+			return nil, err
+		}
+		file, err := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if ok && fn.Recv == nil && match(fn.Name.Name) {
+				found[fn.Name.Name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func handlerMethods(handlers []string) []string {
+	present := map[string]bool{}
+	for _, h := range handlers {
+		present[h] = true
+	}
+
+	methods := []string{}
+	for _, mh := range methodHandlers {
+		if present[mh.Handler] {
+			methods = append(methods, mh.Method)
+		}
+	}
+	return methods
+}
+
+func methodHandlerName(method string) string {
+	for _, mh := range methodHandlers {
+		if mh.Method == method {
+			return mh.Handler
+		}
+	}
+	return ""
+}
+
+func withHandlerName(req *http.Request, name string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), handlerNameKey{}, name))
+}
+
+func HandlerName(req *http.Request) string {
+	if req != nil {
+		if name, ok := req.Context().Value(handlerNameKey{}).(string); ok && name != "" {
+			return name
+		}
+	}
+	return defaultHandlerName
+}
+
+func MainPackageSource(path string) ([]byte, error) {
+	return RenamePackageSource(path, "main")
+}
+
+func RenamePackageSource(path, name string) ([]byte, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), path, src, parser.PackageClauseOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	start := int(file.Name.Pos()) - 1
+	end := int(file.Name.End()) - 1
+
+	out := make([]byte, 0, len(src))
+	out = append(out, src[:start]...)
+	out = append(out, name...)
+	out = append(out, src[end:]...)
+	return out, nil
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDetectHandlers_FindsHandlersAcrossFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	_ = os.WriteFile(filepath.Join(dir, "get.go"), []byte(`package users
+
+import "net/http"
+
+func HandleGet(r *http.Request, p map[string]string) (map[string]interface{}, error) { return nil, nil }
+`), 0644)
+	_ = os.WriteFile(filepath.Join(dir, "post.go"), []byte(`package users
+
+import "net/http"
+
+type svc struct{}
+
+func (svc) HandleDelete() {}
+
+func HandlePost(r *http.Request, p map[string]string) (map[string]interface{}, error) { return nil, nil }
+func helper() {}
+`), 0644)
+	_ = os.WriteFile(filepath.Join(dir, "post_test.go"), []byte(`package users
+
+func HandlePut() {}
+`), 0644)
+
+	handlers, err := DetectHandlers(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"HandleGet", "HandlePost"}
+	if !reflect.DeepEqual(handlers, expected) {
+		t.Errorf("expected %v, got %v", expected, handlers)
+	}
+
+	if methods := handlerMethods(handlers); !reflect.DeepEqual(methods, []string{"GET", "POST"}) {
+		t.Errorf("expected [GET POST], got %v", methods)
+	}
+}
+
+func TestDetectHandlers_ParseError(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "index.go"), []byte("not go"), 0644)
+
+	if _, err := DetectHandlers(dir); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestDetectComponentHandlers(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "latest.server.go"), []byte(`package widgets
+
+func HandleLatestPosts() {}
+func HandleRequest()     {}
+func Handle()            {}
+func handleHidden()      {}
+`), 0644)
+	_ = os.WriteFile(filepath.Join(dir, "card.server.go"), []byte("package widgets\n\nfunc HandleCard() {}\n"), 0644)
+
+	handlers, err := DetectComponentHandlers(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(handlers, []string{"HandleCard", "HandleLatestPosts"}) {
+		t.Errorf("unexpected component handlers %v", handlers)
+	}
+}
+
+func TestHandlerName_DefaultAndOverride(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	if got := HandlerName(req); got != "HandleRequest" {
+		t.Errorf("expected HandleRequest, got %s", got)
+	}
+
+	if got := HandlerName(withHandlerName(req, "HandlePost")); got != "HandlePost" {
+		t.Errorf("expected HandlePost, got %s", got)
+	}
+
+	if got := HandlerName(nil); got != "HandleRequest" {
+		t.Errorf("expected HandleRequest for nil request, got %s", got)
+	}
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"plugin"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var hotBuildCommand = exec.Command
+var pluginVersions sync.Map
+var versionedPluginPattern = regexp.MustCompile(`\.v\d+\.so$`)
+
+type stalePlugin struct{}
+
+func (stalePlugin) Lookup(string) (plugin.Symbol, error) {
+	return nil, ErrPluginNotFound
+}
+
+func changedPlugins(changed []string) []string {
+	dirs := map[string]bool{}
+	for _, path := range changed {
+		if filepath.Ext(path) == ".go" && !strings.HasSuffix(path, "_test.go") {
+			dirs[filepath.Dir(path)] = true
+		}
+	}
+
+	plugins := []string{}
+	for dir := range dirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.so"))
+		for _, soPath := range matches {
+			if versionedPluginPattern.MatchString(soPath) {
+				continue
+			}
+			if fileExists(strings.TrimSuffix(soPath, ".so") + ".go") {
+				plugins = append(plugins, soPath)
+			}
+		}
+	}
+	return plugins
+}
+
+func reloadPlugins(changed []string, out io.Writer) {
+	for _, soPath := range changedPlugins(changed) {
+		if err := reloadPlugin(soPath); err != nil {
+			pluginCache.Store(soPath, stalePlugin{})
+			logf(out, "⚠️ Could not hot reload %s, falling back to subprocess: %v\n", soPath, err)
+			continue
+		}
+		logf(out, "🔁 Reloaded plugin %s\n", soPath)
+	}
+}
+
+func reloadPlugin(soPath string) error {
+	lock := getOrCreateCompileLock(soPath)
+	lock.Lock()
+	defer lock.Unlock()
+
+	absPath, err := filepath.Abs(strings.TrimSuffix(soPath, ".so") + ".go")
+	if err != nil {
+		return err
+	}
+	modRoot, _, err := findGoModRoot(absPath)
+	if err != nil {
+		return fmt.Errorf("could not resolve go.mod: %w", err)
+	}
+
+	next, _ := pluginVersions.Load(soPath)
+	version, _ := next.(int)
+	version++
+	pluginVersions.Store(soPath, version)
+
+	hash := sha256.Sum256([]byte(absPath))
+	srcDir := filepath.Join(modRoot, barryTmpDir, "hot", fmt.Sprintf("%x", hash[:8]))
+	_ = os.RemoveAll(srcDir)
+	if err := osMkdirAll(srcDir, os.ModePerm); err != nil {
+		return fmt.Errorf("could not create temp dir: %w", err)
+	}
+
+	sources, err := pluginSources(absPath)
+	if err != nil {
+		return err
+	}
+
+	files := []string{}
+	for _, path := range sources {
+		src, err := MainPackageSource(path)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", path, err)
+		}
+		file := filepath.Join(srcDir, filepath.Base(path))
+		if err := osWriteFile(file, src, 0644); err != nil {
+			return fmt.Errorf("could not write temp file: %w", err)
+		}
+		files = append(files, file)
+	}
+
+	versionFile := filepath.Join(srcDir, "barry_version.go")
+	if err := osWriteFile(versionFile, []byte(fmt.Sprintf("package main\n\nconst barryPluginVersion = %d\n", version)), 0644); err != nil {
+		return fmt.Errorf("could not write temp file: %w", err)
+	}
+	files = append(files, versionFile)
+
+	versioned, err := filepath.Abs(strings.TrimSuffix(soPath, ".so") + fmt.Sprintf(".v%d.so", version))
+	if err != nil {
+		return err
+	}
+
+	args := append([]string{"build", "-buildmode=plugin", "-o", versioned}, files...)
+	cmd := hotBuildCommand("go", args...)
+	cmd.Dir = modRoot
+	var outBuf, errBuf bytes.Buffer
+	cmd.Stdout = &outBuf
+	cmd.Stderr = io.MultiWriter(os.Stderr, &errBuf)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("build failed: %v\n%s", err, errBuf.String())
+	}
+
+	p, err := loadPluginFunc(versioned)
+	if err != nil {
+		return err
+	}
+	pluginCache.Store(soPath, p)
+
+	if version > 1 {
+		_ = os.Remove(strings.TrimSuffix(soPath, ".so") + fmt.Sprintf(".v%d.so", version-1))
+	}
+	return nil
+}
+
+func (r *Router) reloadChanged(paths []string) {
+	r.loadRoutes()
+	r.loadRules()
+
+	apiChanged, componentsChanged := false, false
+	for _, path := range paths {
+		r.layoutCache.Delete(path)
+		switch strings.SplitN(filepath.ToSlash(path), "/", 2)[0] {
+		case "api":
+			apiChanged = true
+		case "components":
+			componentsChanged = true
+		}
+	}
+	if apiChanged {
+		r.loadApiRoutes()
+	}
+	if componentsChanged {
+		r.loadComponentFiles()
+	}
+	if r.env == "dev" {
+		reloadPlugins(paths, r.logger)
+	}
+}
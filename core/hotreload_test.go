@@ -0,0 +1,169 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"plugin"
+	"strings"
+	"testing"
+)
+
+func TestChangedPlugins_OnlyRebuildsPluginsBesideChangedGoFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "routes", "blog")
+	_ = os.MkdirAll(dir, 0755)
+	for _, name := range []string{"index.server.go", "index.server.so", "index.server.v3.so", "orphan.so", "helper.go"} {
+		_ = os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644)
+	}
+
+	got := changedPlugins([]string{filepath.Join(dir, "helper.go"), filepath.Join(dir, "index.html")})
+	if len(got) != 1 || got[0] != filepath.Join(dir, "index.server.so") {
+		t.Errorf("expected only index.server.so, got %v", got)
+	}
+
+	if got := changedPlugins([]string{filepath.Join(dir, "index.html"), filepath.Join(dir, "x_test.go")}); len(got) != 0 {
+		t.Errorf("expected no plugins for non-source changes, got %v", got)
+	}
+}
+
+type versionPlugin struct{ version string }
+
+func (p versionPlugin) Lookup(string) (plugin.Symbol, error) {
+	return func(*http.Request, map[string]string) (map[string]interface{}, error) {
+		return map[string]interface{}{"version": p.version}, nil
+	}, nil
+}
+
+func setupHotReload(t *testing.T, build func(out string) *exec.Cmd) string {
+	t.Helper()
+	tmp := t.TempDir()
+	wd, _ := os.Getwd()
+	_ = os.Chdir(tmp)
+
+	originalBuild := hotBuildCommand
+	originalLoad := loadPluginFunc
+	originalMod := findGoModRoot
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+		hotBuildCommand = originalBuild
+		loadPluginFunc = originalLoad
+		findGoModRoot = originalMod
+		pluginCache.Delete("routes/blog/index.server.so")
+		pluginVersions.Delete("routes/blog/index.server.so")
+	})
+
+	findGoModRoot = func(string) (string, string, error) {
+		return tmp, "example.com/site", nil
+	}
+	hotBuildCommand = func(name string, args ...string) *exec.Cmd {
+		for i, arg := range args {
+			if arg == "-o" {
+				return build(args[i+1])
+			}
+		}
+		return exec.Command("false")
+	}
+	loadPluginFunc = func(path string) (pluginWithLookup, error) {
+		return versionPlugin{version: filepath.Base(path)}, nil
+	}
+
+	files := map[string]string{
+		"routes/blog/index.server.go":       "package blog\n\nfunc HandleRequest() {}\n",
+		"routes/blog/helper.go":             "package blog\n\nfunc helper() {}\n",
+		"routes/blog/_middleware.server.go": "package blog\n\nfunc Middleware() {}\n",
+		"routes/blog/index.server.so":       "plugin",
+	}
+	for path, content := range files {
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		_ = os.WriteFile(path, []byte(content), 0644)
+	}
+	return tmp
+}
+
+func TestReloadPlugins_SwapsInVersionedBuild(t *testing.T) {
+	setupHotReload(t, func(out string) *exec.Cmd {
+		return exec.Command("touch", out)
+	})
+
+	call := func() interface{} {
+		req := httptest.NewRequest(http.MethodGet, "/blog", nil)
+		result, err := LoadPluginAndCall("routes/blog/index.server.go", req, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result["version"]
+	}
+
+	reloadPlugins([]string{"routes/blog/helper.go"}, nil)
+	if got := call(); got != "index.server.v1.so" {
+		t.Errorf("expected v1 plugin, got %v", got)
+	}
+
+	srcDir, _ := filepath.Glob(filepath.Join(barryTmpDir, "hot", "*"))
+	if len(srcDir) != 1 {
+		t.Fatalf("expected one hot source dir, got %v", srcDir)
+	}
+	for _, name := range []string{"index.server.go", "helper.go"} {
+		src, _ := os.ReadFile(filepath.Join(srcDir[0], name))
+		if !strings.HasPrefix(string(src), "package main") {
+			t.Errorf("expected %s to be rewritten to package main, got %q", name, src)
+		}
+	}
+	if fileExists(filepath.Join(srcDir[0], "_middleware.server.go")) {
+		t.Error("expected middleware to be left out of the handler plugin")
+	}
+
+	reloadPlugins([]string{"routes/blog/index.server.go"}, nil)
+	if got := call(); got != "index.server.v2.so" {
+		t.Errorf("expected v2 plugin, got %v", got)
+	}
+	if fileExists("routes/blog/index.server.v1.so") || !fileExists("routes/blog/index.server.v2.so") {
+		t.Error("expected previous versioned plugin to be removed")
+	}
+}
+
+func TestReloadPlugins_FailedBuildFallsBackToSubprocess(t *testing.T) {
+	setupHotReload(t, func(string) *exec.Cmd {
+		return exec.Command("false")
+	})
+
+	reloadPlugins([]string{"routes/blog/index.server.go"}, nil)
+	if val, _ := pluginCache.Load("routes/blog/index.server.so"); val != (stalePlugin{}) {
+		t.Errorf("expected plugin to be marked stale, got %#v", val)
+	}
+
+	result, err := LoadPluginAndCall("routes/blog/index.server.go", httptest.NewRequest(http.MethodGet, "/blog", nil), nil)
+	if result != nil || err != nil {
+		t.Errorf("expected stale plugin to be skipped, got %v, %v", result, err)
+	}
+}
+
+func TestReloadChanged_RefreshesAPIRoutesAndComponents(t *testing.T) {
+	setupExecSite(t)
+	r := NewRouter(Config{OutputDir: "cache"}, RuntimeContext{Env: "prod"}).(*Router)
+
+	files := map[string]string{
+		"api/users/index.go":      "package users",
+		"components/card.html":    `{{ define "Card" }}card{{ end }}`,
+		"routes/about/index.html": "about",
+	}
+	for path, content := range files {
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		_ = os.WriteFile(path, []byte(content), 0644)
+	}
+	r.layoutCache.Store("routes/about/index.html", "components/layouts/base.html")
+
+	r.reloadChanged([]string{"api/users/index.go", "components/card.html", "routes/about/index.html"})
+
+	if _, _, ok := r.matchApiRoute("users"); !ok {
+		t.Error("expected new api route to be loaded")
+	}
+	if !containsString(r.components(), filepath.Join("components", "card.html")) {
+		t.Errorf("expected new component, got %v", r.components())
+	}
+	if _, ok := r.layoutCache.Load("routes/about/index.html"); ok {
+		t.Error("expected layout cache entry to be cleared")
+	}
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+const layoutServerFile = "layout.server.go"
+const defaultLayoutKey = "Layout"
+
+func layoutServerPath(layoutPath string) string {
+	return filepath.Join(filepath.Dir(layoutPath), layoutServerFile)
+}
+
+func (r *Router) layoutKey() string {
+	if r.config.LayoutKey != "" {
+		return r.config.LayoutKey
+	}
+	return defaultLayoutKey
+}
+
+func (r *Router) layoutData(req *http.Request, layoutPath string, params map[string]string) (map[string]interface{}, error) {
+	if layoutPath == "" {
+		return nil, nil
+	}
+	serverPath := layoutServerPath(layoutPath)
+	if !r.fileExists(serverPath) {
+		return nil, nil
+	}
+
+	return ExecuteServerFile(serverPath, withHandlerName(req, defaultHandlerName), params)
+}
+
+func (r *Router) mergeLayoutData(data, layout map[string]interface{}) {
+	if layout == nil {
+		return
+	}
+	key := r.layoutKey()
+	if page, ok := data[key].(map[string]interface{}); ok {
+		for k, v := range page {
+			layout[k] = v
+		}
+	}
+	data[key] = layout
+}
+
+func (r *Router) detachedRequest(req *http.Request) (*http.Request, context.CancelFunc) {
+	if req.Context().Err() == nil {
+		return req, func() {}
+	}
+	ctx := context.WithoutCancel(req.Context())
+	if timeout := r.execTimeout(strings.TrimPrefix(req.URL.Path, "/")); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		return req.WithContext(ctx), cancel
+	}
+	return req.WithContext(ctx), func() {}
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupLayoutSite(t *testing.T, layout string) *[]string {
+	t.Helper()
+	return stubSite(t, map[string]string{
+		"components/layouts/base.html":        layout,
+		"components/layouts/layout.server.go": "package layouts",
+		"routes/page/index.html":              "<!-- layout: components/layouts/base.html -->\n{{ define \"content\" }}<p>{{ .Title }}</p>{{ end }}",
+		"routes/page/index.server.go":         "package page",
+	}, func(path string, req *http.Request, _ map[string]string) (map[string]interface{}, error) {
+		switch filepath.Base(path) {
+		case layoutServerFile:
+			if err := req.Context().Err(); err != nil {
+				return nil, err
+			}
+			recordResponseMeta(req, ResponseMeta{Status: http.StatusTeapot})
+			return map[string]interface{}{"Nav": "home", "Active": "none"}, nil
+		default:
+			if req.URL.Query().Get("fail") == "1" {
+				return nil, HTTPError(http.StatusForbidden, "")
+			}
+			return map[string]interface{}{"Title": "Page", "Layout": map[string]interface{}{"Active": "page"}}, nil
+		}
+	})
+}
+
+func TestServeHTTP_LayoutServerDataMergedUnderLayout(t *testing.T) {
+	calls := setupLayoutSite(t, `{{ define "layout" }}<nav>{{ .Layout.Nav }}/{{ .Layout.Active }}</nav>{{ template "content" . }}{{ end }}`)
+	router := NewRouter(Config{OutputDir: "cache"}, RuntimeContext{Env: "prod"})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/page", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); body != "<nav>home/page</nav><p>Page</p>" {
+		t.Errorf("unexpected body %q", body)
+	}
+	if len(*calls) != 2 || (*calls)[1] != "components/layouts/layout.server.go:HandleRequest" {
+		t.Errorf("expected layout server file to run with HandleRequest, got %v", *calls)
+	}
+}
+
+func TestServeHTTP_LayoutKeyIsConfigurable(t *testing.T) {
+	setupLayoutSite(t, `{{ define "layout" }}<nav>{{ .Site.Nav }}</nav>{{ template "content" . }}{{ end }}`)
+	router := NewRouter(Config{OutputDir: "cache", LayoutKey: "Site"}, RuntimeContext{Env: "prod"})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/page", nil))
+
+	if body := rec.Body.String(); body != "<nav>home</nav><p>Page</p>" {
+		t.Errorf("unexpected body %q", body)
+	}
+}
+
+func TestServeHTTP_LayoutServerErrorUsesErrorHandling(t *testing.T) {
+	setupLayoutSite(t, `{{ define "layout" }}{{ template "content" . }}{{ end }}`)
+	original := ExecuteServerFile
+	ExecuteServerFile = func(path string, req *http.Request, params map[string]string) (map[string]interface{}, error) {
+		if filepath.Base(path) == layoutServerFile {
+			return nil, errors.New("db down")
+		}
+		return original(path, req, params)
+	}
+
+	router := NewRouter(Config{OutputDir: "cache"}, RuntimeContext{Env: "prod"})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/page", nil))
+
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "Layout logic error: db down") {
+		t.Errorf("expected layout error, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRenderErrorPage_RunsLayoutServerFile(t *testing.T) {
+	setupLayoutSite(t, `{{ define "layout" }}<nav>{{ .Layout.Nav }}</nav>{{ template "content" . }}{{ end }}`)
+	router := NewRouter(Config{OutputDir: "cache"}, RuntimeContext{Env: "prod"})
+
+	for _, target := range []string{"/missing", "/page?fail=1"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+		if !strings.Contains(rec.Body.String(), "<nav>home</nav><h1>Error") {
+			t.Errorf("%s: expected error page with layout data, got %q", target, rec.Body.String())
+		}
+	}
+}
+
+func TestRenderErrorPage_LayoutDataAfterTimeout(t *testing.T) {
+	setupLayoutSite(t, `{{ define "layout" }}<nav>{{ .Layout.Nav }}</nav>{{ template "content" . }}{{ end }}`)
+	blockUntilDone(t)
+	original := ExecuteServerFile
+	ExecuteServerFile = func(path string, req *http.Request, params map[string]string) (map[string]interface{}, error) {
+		if filepath.Base(path) == layoutServerFile {
+			if err := req.Context().Err(); err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"Nav": "home"}, nil
+		}
+		return original(path, req, params)
+	}
+
+	router := NewRouter(Config{OutputDir: "cache", Timeout: "20ms"}, RuntimeContext{Env: "prod"})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	if rec.Code != http.StatusGatewayTimeout || !strings.Contains(rec.Body.String(), "<nav>home</nav><h1>Error 504") {
+		t.Errorf("expected 504 page with layout data, got %d %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,155 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+const middlewareFileName = "_middleware.server.go"
+const middlewareHandlerName = "Middleware"
+
+type middlewareDataKey struct{}
+
+type middlewareOutcome struct {
+	Params   map[string]string
+	Data     map[string]interface{}
+	Headers  map[string]string
+	Cookies  []string
+	Status   int
+	Redirect string
+}
+
+func (o middlewareOutcome) shortCircuits() bool {
+	return o.Redirect != "" || (o.Status != 0 && o.Status != http.StatusOK)
+}
+
+var ExecuteMiddleware = func(filePath string, req *http.Request, params map[string]string) (map[string]interface{}, error) {
+	return ExecuteServerFile(filePath, withHandlerName(req, middlewareHandlerName), params)
+}
+
+func findMiddleware(fsys fs.FS, root, dir string) []string {
+	chain := []string{}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return chain
+	}
+
+	current := root
+	parts := []string{}
+	if rel != "." {
+		parts = strings.Split(filepath.ToSlash(rel), "/")
+	}
+
+	for i := 0; i <= len(parts); i++ {
+		if i > 0 {
+			current = filepath.Join(current, parts[i-1])
+		}
+		candidate := filepath.Join(current, middlewareFileName)
+		if _, err := siteStat(fsys, candidate); err == nil {
+			chain = append(chain, candidate)
+		}
+	}
+
+	return chain
+}
+
+func runMiddleware(chain []string, req *http.Request, params map[string]string) (middlewareOutcome, error) {
+	outcome := middlewareOutcome{
+		Params:  map[string]string{},
+		Data:    map[string]interface{}{},
+		Headers: map[string]string{},
+	}
+	for k, v := range params {
+		outcome.Params[k] = v
+	}
+
+	for _, path := range chain {
+		mwReq, slot := withResponseMeta(req)
+		result, err := ExecuteMiddleware(path, mwReq, outcome.Params)
+		if err != nil {
+			if IsNotFoundError(err) {
+				return outcome, err
+			}
+			return outcome, fmt.Errorf("middleware %s: %w", path, err)
+		}
+
+		meta := slot.get()
+		for k, v := range meta.Params {
+			outcome.Params[k] = v
+		}
+		for k, v := range meta.Headers {
+			outcome.Headers[k] = v
+		}
+		outcome.Cookies = append(outcome.Cookies, meta.Cookies...)
+		if meta.Status != 0 {
+			outcome.Status = meta.Status
+		}
+		if meta.Redirect != "" {
+			outcome.Redirect = meta.Redirect
+		}
+		for k, v := range result {
+			outcome.Data[k] = v
+		}
+
+		if outcome.shortCircuits() {
+			break
+		}
+	}
+
+	return outcome, nil
+}
+
+func (r *Router) applyMiddleware(w http.ResponseWriter, req *http.Request, chain []string, params map[string]string, isAPI bool) (*http.Request, map[string]string, bool) {
+	if len(chain) == 0 {
+		return req, params, true
+	}
+
+	outcome, err := runMiddleware(chain, req, params)
+	if err != nil {
+		r.handleExecError(w, req, err, isAPI, "Middleware error: ")
+		return req, nil, false
+	}
+
+	for k, v := range outcome.Headers {
+		w.Header().Set(k, v)
+	}
+	for _, cookie := range outcome.Cookies {
+		w.Header().Add("Set-Cookie", cookie)
+	}
+
+	if outcome.Redirect != "" {
+		status := outcome.Status
+		if status < 300 || status > 399 {
+			status = http.StatusFound
+		}
+		http.Redirect(w, req, outcome.Redirect, status)
+		return req, nil, false
+	}
+
+	if outcome.Status != 0 && outcome.Status != http.StatusOK {
+		message := http.StatusText(outcome.Status)
+		if isAPI {
+			http.Error(w, message, outcome.Status)
+		} else {
+			r.renderErrorPage(w, req, outcome.Status, message)
+		}
+		return req, nil, false
+	}
+
+	if len(outcome.Data) > 0 {
+		req = req.WithContext(context.WithValue(req.Context(), middlewareDataKey{}, outcome.Data))
+	}
+
+	return req, outcome.Params, true
+}
+
+func middlewareData(req *http.Request) map[string]interface{} {
+	if data, ok := req.Context().Value(middlewareDataKey{}).(map[string]interface{}); ok {
+		return data
+	}
+	return nil
+}
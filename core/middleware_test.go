@@ -0,0 +1,215 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindMiddleware_OutermostFirst(t *testing.T) {
+	t.Cleanup(cleanupTestArtifacts)
+
+	_ = os.MkdirAll("routes/admin/users/_id", 0755)
+	_ = os.WriteFile("routes/_middleware.server.go", []byte("package routes"), 0644)
+	_ = os.WriteFile("routes/admin/_middleware.server.go", []byte("package admin"), 0644)
+	_ = os.WriteFile("routes/admin/users/_id/_middleware.server.go", []byte("package id"), 0644)
+
+	chain := findMiddleware(nil, "routes", "routes/admin/users/_id")
+	expected := []string{
+		filepath.Join("routes", "_middleware.server.go"),
+		filepath.Join("routes", "admin", "_middleware.server.go"),
+		filepath.Join("routes", "admin", "users", "_id", "_middleware.server.go"),
+	}
+
+	if !reflect.DeepEqual(chain, expected) {
+		t.Errorf("expected %v, got %v", expected, chain)
+	}
+
+	if chain := findMiddleware(nil, "routes", "elsewhere"); len(chain) != 0 {
+		t.Errorf("expected no middleware outside root, got %v", chain)
+	}
+}
+
+func TestRunMiddleware_MergesAndShortCircuits(t *testing.T) {
+	original := ExecuteMiddleware
+	defer func() { ExecuteMiddleware = original }()
+
+	calls := []string{}
+	ExecuteMiddleware = func(path string, req *http.Request, params map[string]string) (map[string]interface{}, error) {
+		calls = append(calls, path)
+		switch path {
+		case "outer":
+			recordResponseMeta(req, ResponseMeta{
+				Params:  map[string]string{"locale": "en"},
+				Headers: map[string]string{"X-Frame-Options": "DENY"},
+			})
+			return map[string]interface{}{"User": "ada"}, nil
+		case "auth":
+			if params["locale"] != "en" {
+				t.Errorf("expected params from outer middleware, got %v", params)
+			}
+			recordResponseMeta(req, ResponseMeta{Redirect: "/login", Status: 307})
+			return nil, nil
+		}
+		return nil, nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	outcome, err := runMiddleware([]string{"outer", "auth", "never"}, req, map[string]string{"id": "1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(calls, []string{"outer", "auth"}) {
+		t.Errorf("expected chain to stop after redirect, got %v", calls)
+	}
+	if outcome.Redirect != "/login" || outcome.Status != 307 {
+		t.Errorf("unexpected redirect outcome: %+v", outcome)
+	}
+	if outcome.Params["id"] != "1" || outcome.Params["locale"] != "en" {
+		t.Errorf("unexpected params: %v", outcome.Params)
+	}
+	if outcome.Headers["X-Frame-Options"] != "DENY" || outcome.Data["User"] != "ada" {
+		t.Errorf("unexpected headers/data: %+v", outcome)
+	}
+}
+
+func TestRouter_MiddlewareRedirectsBeforePage(t *testing.T) {
+	cfg, cleanup := setupRouterTestEnv(t)
+	defer cleanup()
+
+	_ = os.WriteFile("routes/test/_middleware.server.go", []byte("package test"), 0644)
+
+	original := ExecuteMiddleware
+	defer func() { ExecuteMiddleware = original }()
+	ExecuteMiddleware = func(path string, req *http.Request, params map[string]string) (map[string]interface{}, error) {
+		recordResponseMeta(req, ResponseMeta{Redirect: "/login"})
+		return nil, nil
+	}
+
+	router := NewRouter(cfg, RuntimeContext{Env: "dev"})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	if rec.Code != http.StatusFound {
+		t.Errorf("expected 302, got %d", rec.Code)
+	}
+	if rec.Header().Get("Location") != "/login" {
+		t.Errorf("expected redirect to /login, got %q", rec.Header().Get("Location"))
+	}
+}
+
+func TestRouter_MiddlewareDataReachesTemplate(t *testing.T) {
+	t.Cleanup(cleanupTestArtifacts)
+
+	_ = os.MkdirAll("routes/admin", 0755)
+	_ = os.WriteFile("routes/admin/index.html", []byte(`<!-- layout: layout.html -->
+{{ define "content" }}<h1>{{ .User }} / {{ .Title }}</h1>{{ end }}`), 0644)
+	_ = os.WriteFile("routes/admin/index.server.go", []byte(""), 0644)
+	_ = os.WriteFile("routes/_middleware.server.go", []byte("package routes"), 0644)
+	_ = os.WriteFile("layout.html", []byte(`{{ define "layout" }}<html><body>{{ template "content" . }}</body></html>{{ end }}`), 0644)
+	_ = os.MkdirAll("components", 0755)
+
+	originalMiddleware := ExecuteMiddleware
+	originalServer := ExecuteServerFile
+	defer func() {
+		ExecuteMiddleware = originalMiddleware
+		ExecuteServerFile = originalServer
+	}()
+
+	ExecuteMiddleware = func(path string, req *http.Request, params map[string]string) (map[string]interface{}, error) {
+		recordResponseMeta(req, Response{
+			Params:  map[string]string{"role": "admin"},
+			Headers: map[string]string{"X-Section": "admin"},
+		}.ResponseMeta())
+		return map[string]interface{}{"User": "ada"}, nil
+	}
+	ExecuteServerFile = func(_ string, _ *http.Request, params map[string]string) (map[string]interface{}, error) {
+		return map[string]interface{}{"Title": "Dashboard for " + params["role"]}, nil
+	}
+
+	router := NewRouter(Config{OutputDir: t.TempDir()}, RuntimeContext{Env: "dev"})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "ada / Dashboard for admin") {
+		t.Errorf("expected middleware data and params, got: %s", rec.Body.String())
+	}
+	if rec.Header().Get("X-Section") != "admin" {
+		t.Errorf("expected middleware header, got %v", rec.Header())
+	}
+}
+
+func TestRouter_MiddlewareStatusForAPI(t *testing.T) {
+	original := ExecuteMiddleware
+	defer func() { ExecuteMiddleware = original }()
+	ExecuteMiddleware = func(path string, req *http.Request, params map[string]string) (map[string]interface{}, error) {
+		recordResponseMeta(req, ResponseMeta{Status: 401})
+		return nil, nil
+	}
+
+	defer func() { ExecuteAPIFile = _origExecuteAPIFile }()
+	ExecuteAPIFile = func(path string, req *http.Request, params map[string]string) ([]byte, error) {
+		t.Error("API handler should not run")
+		return nil, nil
+	}
+
+	r := &Router{env: "dev"}
+	r.setApiRoutes([]ApiRoute{{
+		ServerPath: "api/private/index.go",
+		FilePath:   "api/private",
+		Middleware: []string{"api/_middleware.server.go"},
+	}})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/private", nil))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", rec.Code)
+	}
+}
+
+func TestRouter_MiddlewareErrorReturns500(t *testing.T) {
+	original := ExecuteMiddleware
+	defer func() { ExecuteMiddleware = original }()
+	ExecuteMiddleware = func(path string, req *http.Request, params map[string]string) (map[string]interface{}, error) {
+		return nil, errors.New("boom")
+	}
+
+	r := &Router{env: "dev"}
+	r.setApiRoutes([]ApiRoute{{
+		ServerPath: "api/private/index.go",
+		FilePath:   "api/private",
+		Middleware: []string{"api/_middleware.server.go"},
+	}})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/private", nil))
+
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "boom") {
+		t.Errorf("expected 500 with middleware error, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestMainPackageSource_RewritesPackageClause(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "_middleware.server.go")
+	_ = os.WriteFile(path, []byte("// admin middleware\npackage admin\n\nfunc Middleware() {}\n"), 0644)
+
+	src, err := MainPackageSource(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(src) != "// admin middleware\npackage main\n\nfunc Middleware() {}\n" {
+		t.Errorf("unexpected source: %q", src)
+	}
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"plugin"
@@ -10,7 +11,7 @@ import (
 )
 
 var pluginCache sync.Map
-var ErrInvalidPlugin = errors.New("invalid plugin: missing HandleRequest")
+var ErrInvalidPlugin = errors.New("invalid plugin: missing handler")
 
 type pluginWithLookup interface {
 	Lookup(string) (plugin.Symbol, error)
@@ -35,22 +36,71 @@ func LoadPluginAndCall(filePath string, req *http.Request, params map[string]str
 	if ok {
 		p = val.(pluginWithLookup)
 	} else {
-		p, err = loadPluginFunc(soPath)
+		lock := getOrCreateCompileLock(soPath)
+		lock.Lock()
+		if val, ok := pluginCache.Load(soPath); ok {
+			p = val.(pluginWithLookup)
+		} else {
+			p, err = loadPluginFunc(soPath)
+			if err == nil {
+				pluginCache.Store(soPath, p)
+			}
+		}
+		if err != nil {
+			pluginCache.Store(soPath, stalePlugin{})
+			logf(requestLogger(req), "⚠️ Could not open plugin %s, falling back to subprocess: %v\n", soPath, err)
+		}
+		lock.Unlock()
 		if err != nil {
-			return nil, err
+			return nil, nil
 		}
-		pluginCache.Store(soPath, p)
 	}
 
-	sym, err := p.Lookup("HandleRequest")
+	if _, stale := p.(stalePlugin); stale {
+		return nil, nil
+	}
+
+	sym, err := p.Lookup(HandlerName(req))
 	if err != nil {
 		return nil, ErrInvalidPlugin
 	}
 
-	handler, ok := sym.(func(*http.Request, map[string]string) (map[string]interface{}, error))
+	handler, ok := handlerFunc(sym)
 	if !ok {
 		return nil, ErrInvalidPlugin
 	}
 
-	return handler(req, params)
+	return callWithContext(req, func() (map[string]interface{}, error) {
+		return handler.call(req, params)
+	})
+}
+
+func callWithContext(req *http.Request, fn func() (map[string]interface{}, error)) (map[string]interface{}, error) {
+	ctx := req.Context()
+	if ctx.Done() == nil {
+		return fn()
+	}
+
+	type outcome struct {
+		result map[string]interface{}
+		err    error
+	}
+	done := make(chan outcome, 1)
+
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- outcome{err: fmt.Errorf("panic: %v", p)}
+			}
+		}()
+		result, err := fn()
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case out := <-done:
+		return out.result, out.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
@@ -1,13 +1,17 @@
 package core
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"plugin"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadPluginAndCall_PluginNotFound(t *testing.T) {
@@ -36,11 +40,40 @@ func TestLoadPluginAndCall_InvalidSymbol(t *testing.T) {
 	soPath := filepath.Join(tmp, "bad.so")
 	_ = os.WriteFile(soPath, []byte{}, 0644)
 
+	defer pluginCache.Delete(soPath)
+
 	req, _ := http.NewRequest(http.MethodGet, "/", nil)
-	_, err := LoadPluginAndCall(strings.TrimSuffix(soPath, ".so")+".go", req, nil)
+	result, err := LoadPluginAndCall(strings.TrimSuffix(soPath, ".so")+".go", req, nil)
+
+	if result != nil || err != nil {
+		t.Errorf("expected plugin open failure to fall back to subprocess, got: %v, %v", result, err)
+	}
+	if val, _ := pluginCache.Load(soPath); val != (stalePlugin{}) {
+		t.Errorf("expected plugin to be marked stale, got %#v", val)
+	}
+}
+
+func TestLoadPluginAndCall_OpenFailureLogsToRequestLogger(t *testing.T) {
+	original := loadPluginFunc
+	defer func() { loadPluginFunc = original }()
+
+	loadPluginFunc = func(path string) (pluginWithLookup, error) {
+		return nil, errors.New("mock plugin open failure")
+	}
+
+	tmp := t.TempDir()
+	soPath := filepath.Join(tmp, "logged.so")
+	_ = os.WriteFile(soPath, []byte{}, 0644)
+
+	defer pluginCache.Delete(soPath)
 
-	if err == nil || !strings.Contains(err.Error(), "mock plugin open failure") {
-		t.Errorf("expected plugin open failure, got: %v", err)
+	var out bytes.Buffer
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(withLogger(req.Context(), &out))
+	_, _ = LoadPluginAndCall(strings.TrimSuffix(soPath, ".so")+".go", req, nil)
+
+	if !strings.Contains(out.String(), "Could not open plugin") {
+		t.Errorf("expected open failure on the request logger, got %q", out.String())
 	}
 }
 
@@ -101,6 +134,41 @@ func TestLoadPluginAndCall_Success(t *testing.T) {
 	}
 }
 
+type typedPluginData struct {
+	Count int
+}
+
+type typedPlugin struct{}
+
+func (typedPlugin) Lookup(name string) (plugin.Symbol, error) {
+	return func(r *http.Request, p map[string]string) (interface{}, error) {
+		return typedPluginData{Count: 3000000}, nil
+	}, nil
+}
+
+func TestLoadPluginAndCall_TypedResultKeepsType(t *testing.T) {
+	original := loadPluginFunc
+	defer func() { loadPluginFunc = original }()
+
+	loadPluginFunc = func(path string) (pluginWithLookup, error) {
+		return typedPlugin{}, nil
+	}
+
+	tmp := t.TempDir()
+	soPath := filepath.Join(tmp, "typed.so")
+	_ = os.WriteFile(soPath, []byte("dummy"), 0644)
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	result, err := LoadPluginAndCall(strings.TrimSuffix(soPath, ".so")+".go", req, nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["Count"] != int64(3000000) {
+		t.Errorf("expected Count to stay an integer, got %#v", result["Count"])
+	}
+}
+
 type cachedPlugin struct {
 	wasCalled *bool
 }
@@ -161,3 +229,68 @@ func TestLoadPluginAndCall_LookupFails(t *testing.T) {
 		t.Errorf("expected ErrInvalidPlugin from missing symbol, got: %v", err)
 	}
 }
+
+type methodPlugin struct {
+	looked *string
+}
+
+func (mp methodPlugin) Lookup(name string) (plugin.Symbol, error) {
+	*mp.looked = name
+	return func(r *http.Request, p map[string]string) (map[string]interface{}, error) {
+		return map[string]interface{}{"ok": true}, nil
+	}, nil
+}
+
+func TestLoadPluginAndCall_UsesHandlerNameFromRequest(t *testing.T) {
+	original := loadPluginFunc
+	defer func() { loadPluginFunc = original }()
+
+	var looked string
+	loadPluginFunc = func(path string) (pluginWithLookup, error) {
+		return methodPlugin{looked: &looked}, nil
+	}
+
+	tmp := t.TempDir()
+	soPath := filepath.Join(tmp, "method.so")
+	_ = os.WriteFile(soPath, []byte("fake"), 0644)
+
+	req, _ := http.NewRequest(http.MethodPost, "/", nil)
+	_, err := LoadPluginAndCall(strings.TrimSuffix(soPath, ".so")+".go", withHandlerName(req, "HandlePost"), nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if looked != "HandlePost" {
+		t.Errorf("expected HandlePost lookup, got %s", looked)
+	}
+}
+
+func TestCallWithContext_ReturnsWhenDeadlinePasses(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	release := make(chan struct{})
+	defer close(release)
+
+	_, err := callWithContext(req, func() (map[string]interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	if !IsTimeoutError(err) {
+		t.Errorf("expected deadline error, got %v", err)
+	}
+}
+
+func TestCallWithContext_RecoversPanics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	_, err := callWithContext(req, func() (map[string]interface{}, error) {
+		panic("boom")
+	})
+	if err == nil || !strings.Contains(err.Error(), "panic: boom") {
+		t.Errorf("expected recovered panic, got %v", err)
+	}
+}
@@ -0,0 +1,308 @@
+package core
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+
+	json "github.com/segmentio/encoding/json"
+)
+
+const PluginManifestFile = "barry.plugins.json"
+
+var pluginEnvCommand = exec.Command
+
+type PluginManifest struct {
+	GoVersion string                 `json:"goVersion"`
+	Modules   map[string]string      `json:"modules"`
+	Plugins   map[string]PluginEntry `json:"plugins"`
+}
+
+type PluginEntry struct {
+	Source string `json:"source"`
+	Hash   string `json:"hash"`
+	Deps   string `json:"deps,omitempty"`
+}
+
+type PluginEnv struct {
+	GoVersion string
+	Modules   map[string]string
+}
+
+type PluginIssue struct {
+	Plugin string
+	Reason string
+}
+
+func ReadPluginManifest(path string) (*PluginManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest PluginManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid plugin manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+func WritePluginManifest(path string, manifest *PluginManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func pluginSources(goPath string) ([]string, error) {
+	if strings.HasPrefix(filepath.Base(goPath), "_") {
+		return []string{goPath}, nil
+	}
+	return handlerSources(filepath.Dir(goPath))
+}
+
+func PluginSourceHash(goPath string) (string, error) {
+	sources, err := pluginSources(goPath)
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(sources)
+
+	h := sha256.New()
+	for _, path := range sources {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", filepath.Base(path), len(data))
+		h.Write(data)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+func PluginDepsHash(goPath string) (string, error) {
+	absPath, err := filepath.Abs(goPath)
+	if err != nil {
+		return "", err
+	}
+	modRoot, modName, err := findGoModRoot(absPath)
+	if err != nil {
+		return "", err
+	}
+	sources, err := pluginSources(absPath)
+	if err != nil {
+		return "", err
+	}
+
+	pending := localImports(sources, modName)
+	seen := map[string]bool{}
+	files := []string{}
+	for len(pending) > 0 {
+		pkg := pending[0]
+		pending = pending[1:]
+		if seen[pkg] {
+			continue
+		}
+		seen[pkg] = true
+
+		dir := filepath.Join(modRoot, filepath.FromSlash(strings.TrimPrefix(pkg, modName)))
+		pkgFiles, err := handlerSources(dir)
+		if err != nil {
+			return "", fmt.Errorf("could not read package %s: %w", pkg, err)
+		}
+		files = append(files, pkgFiles...)
+		pending = append(pending, localImports(pkgFiles, modName)...)
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		rel, _ := filepath.Rel(modRoot, path)
+		fmt.Fprintf(h, "%s\x00%d\x00", filepath.ToSlash(rel), len(data))
+		h.Write(data)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+func localImports(paths []string, modName string) []string {
+	imports := []string{}
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		file, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+		for _, spec := range file.Imports {
+			importPath, _ := strconv.Unquote(spec.Path.Value)
+			if importPath == modName || strings.HasPrefix(importPath, modName+"/") {
+				imports = append(imports, importPath)
+			}
+		}
+	}
+	return imports
+}
+
+func ToolchainPluginEnv(dir string) (PluginEnv, error) {
+	env := PluginEnv{Modules: map[string]string{}}
+
+	cmd := pluginEnvCommand("go", "env", "GOVERSION")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return env, fmt.Errorf("go env GOVERSION: %w", err)
+	}
+	env.GoVersion = strings.TrimSpace(string(out))
+
+	cmd = pluginEnvCommand("go", "list", "-e", "-deps", "-f", "{{with .Module}}{{.Path}} {{.Version}}{{with .Replace}} => {{.Path}} {{.Version}}{{end}}{{end}}", "./...")
+	cmd.Dir = dir
+	out, err = cmd.Output()
+	if err != nil {
+		return env, fmt.Errorf("go list -deps: %w", err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		path, version, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if ok {
+			env.Modules[path] = normalizeModuleVersion(version)
+		}
+	}
+	return env, nil
+}
+
+func CurrentPluginEnv() PluginEnv {
+	env := PluginEnv{GoVersion: runtime.Version(), Modules: map[string]string{}}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return env
+	}
+	for _, dep := range info.Deps {
+		version := dep.Version
+		if dep.Replace != nil {
+			version += " => " + dep.Replace.Path + " " + dep.Replace.Version
+		}
+		env.Modules[dep.Path] = normalizeModuleVersion(version)
+	}
+	return env
+}
+
+func normalizeModuleVersion(version string) string {
+	version = strings.ReplaceAll(version, "(devel)", "")
+	return strings.TrimSpace(version)
+}
+
+func CheckPlugins(manifest *PluginManifest, env PluginEnv) []PluginIssue {
+	issues := []PluginIssue{}
+
+	plugins := make([]string, 0, len(manifest.Plugins))
+	for soPath := range manifest.Plugins {
+		plugins = append(plugins, soPath)
+	}
+	sort.Strings(plugins)
+
+	modules := []string{}
+	for path, version := range manifest.Modules {
+		if current, ok := env.Modules[path]; ok && current != version {
+			modules = append(modules, fmt.Sprintf("%s %s, now %s", path, version, current))
+		}
+	}
+	sort.Strings(modules)
+
+	for _, soPath := range plugins {
+		switch {
+		case manifest.GoVersion != env.GoVersion:
+			issues = append(issues, PluginIssue{soPath, fmt.Sprintf("built with %s, running %s", manifest.GoVersion, env.GoVersion)})
+		case len(modules) > 0:
+			issues = append(issues, PluginIssue{soPath, "module versions changed: " + strings.Join(modules, "; ")})
+		case !fileExists(filepath.FromSlash(soPath)):
+			issues = append(issues, PluginIssue{soPath, "plugin file is missing"})
+		default:
+			if reason := sourceIssue(manifest.Plugins[soPath]); reason != "" {
+				issues = append(issues, PluginIssue{soPath, reason})
+			}
+		}
+	}
+
+	for _, soPath := range FindPlugins() {
+		if _, ok := manifest.Plugins[soPath]; !ok {
+			issues = append(issues, PluginIssue{soPath, "not listed in " + PluginManifestFile})
+		}
+	}
+
+	return issues
+}
+
+func sourceIssue(entry PluginEntry) string {
+	source := filepath.FromSlash(entry.Source)
+	hash, err := PluginSourceHash(source)
+	if err != nil {
+		return fmt.Sprintf("could not read sources: %v", err)
+	}
+	if hash != entry.Hash {
+		return "sources changed since build"
+	}
+	if entry.Deps == "" {
+		return ""
+	}
+	deps, err := PluginDepsHash(source)
+	if err != nil {
+		return fmt.Sprintf("could not read dependencies: %v", err)
+	}
+	if deps != entry.Deps {
+		return "dependencies changed since build"
+	}
+	return ""
+}
+
+func FindPlugins() []string {
+	plugins := []string{}
+	for _, root := range []string{"routes", "api", "components"} {
+		_ = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && filepath.Ext(path) == ".so" && !versionedPluginPattern.MatchString(path) {
+				plugins = append(plugins, filepath.ToSlash(path))
+			}
+			return nil
+		})
+	}
+	sort.Strings(plugins)
+	return plugins
+}
+
+func ValidatePlugins(out io.Writer) int {
+	plugins := FindPlugins()
+	if len(plugins) == 0 {
+		return 0
+	}
+
+	manifest, err := ReadPluginManifest(PluginManifestFile)
+	if err != nil {
+		for _, soPath := range plugins {
+			pluginCache.Store(filepath.FromSlash(soPath), stalePlugin{})
+		}
+		logf(out, "⚠️ Skipping %d plugins, %s could not be read: %v\n", len(plugins), PluginManifestFile, err)
+		return len(plugins)
+	}
+
+	issues := CheckPlugins(manifest, CurrentPluginEnv())
+	for _, issue := range issues {
+		pluginCache.Store(filepath.FromSlash(issue.Plugin), stalePlugin{})
+		logf(out, "⚠️ Skipping plugin %s: %s\n", issue.Plugin, issue.Reason)
+	}
+	if len(issues) > 0 {
+		logf(out, "⚠️ Skipped plugins fall back to subprocess execution. Run `barry build` to rebuild them.\n")
+	}
+	return len(issues)
+}
@@ -0,0 +1,253 @@
+package core
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePluginSite(t *testing.T, files map[string]string) {
+	t.Helper()
+	tmp := t.TempDir()
+	wd, _ := os.Getwd()
+	_ = os.Chdir(tmp)
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	for path, content := range files {
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		_ = os.WriteFile(path, []byte(content), 0644)
+	}
+}
+
+func TestPluginSourceHash_ChangesWithAnySourceInDirectory(t *testing.T) {
+	writePluginSite(t, map[string]string{
+		"routes/blog/index.server.go": "package blog\n\nfunc HandleRequest() {}\n",
+		"routes/blog/helper.go":       "package blog\n\nfunc helper() {}\n",
+	})
+
+	before, err := PluginSourceHash("routes/blog/index.server.go")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = os.WriteFile("routes/blog/helper.go", []byte("package blog\n\nfunc helper() int { return 1 }\n"), 0644)
+	after, _ := PluginSourceHash("routes/blog/index.server.go")
+
+	if before == after {
+		t.Error("expected hash to change when a sibling source changes")
+	}
+}
+
+func TestPluginManifest_WriteAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), PluginManifestFile)
+	manifest := &PluginManifest{
+		GoVersion: "go1.24.3",
+		Modules:   map[string]string{"github.com/go-barry/barry": "v1.0.0"},
+		Plugins:   map[string]PluginEntry{"routes/index.server.so": {Source: "routes/index.server.go", Hash: "abc"}},
+	}
+	if err := WritePluginManifest(path, manifest); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := ReadPluginManifest(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.GoVersion != "go1.24.3" || got.Plugins["routes/index.server.so"].Hash != "abc" {
+		t.Errorf("unexpected manifest: %+v", got)
+	}
+
+	_ = os.WriteFile(path, []byte("{"), 0644)
+	if _, err := ReadPluginManifest(path); err == nil {
+		t.Error("expected error for invalid manifest")
+	}
+}
+
+func TestCheckPlugins_ReportsIncompatiblePlugins(t *testing.T) {
+	writePluginSite(t, map[string]string{
+		"routes/index.server.go":      "package routes\n\nfunc HandleRequest() {}\n",
+		"routes/index.server.so":      "so",
+		"routes/blog/index.server.go": "package blog\n\nfunc HandleRequest() {}\n",
+		"routes/blog/index.server.so": "so",
+		"api/users/get.so":            "so",
+		"api/users/get.v2.so":         "so",
+	})
+	hash, _ := PluginSourceHash("routes/index.server.go")
+
+	manifest := &PluginManifest{
+		GoVersion: "go1.24.3",
+		Modules:   map[string]string{"github.com/go-barry/barry": "v1.0.0"},
+		Plugins: map[string]PluginEntry{
+			"routes/index.server.so":      {Source: "routes/index.server.go", Hash: hash},
+			"routes/blog/index.server.so": {Source: "routes/blog/index.server.go", Hash: "stale"},
+			"routes/gone/index.server.so": {Source: "routes/gone/index.server.go", Hash: "x"},
+		},
+	}
+	env := PluginEnv{GoVersion: "go1.24.3", Modules: map[string]string{"github.com/go-barry/barry": "v1.0.0"}}
+
+	issues := CheckPlugins(manifest, env)
+	reasons := map[string]string{}
+	for _, issue := range issues {
+		reasons[issue.Plugin] = issue.Reason
+	}
+
+	if _, ok := reasons["routes/index.server.so"]; ok {
+		t.Errorf("expected up to date plugin to pass, got %q", reasons["routes/index.server.so"])
+	}
+	if reasons["routes/blog/index.server.so"] != "sources changed since build" {
+		t.Errorf("expected changed sources, got %q", reasons["routes/blog/index.server.so"])
+	}
+	if reasons["routes/gone/index.server.so"] != "plugin file is missing" {
+		t.Errorf("expected missing plugin, got %q", reasons["routes/gone/index.server.so"])
+	}
+	if !strings.Contains(reasons["api/users/get.so"], "not listed") {
+		t.Errorf("expected unlisted plugin, got %q", reasons["api/users/get.so"])
+	}
+	if len(issues) != 3 {
+		t.Errorf("expected 3 issues, got %v", issues)
+	}
+
+	env.GoVersion = "go1.25.0"
+	for _, issue := range CheckPlugins(manifest, env) {
+		if strings.HasSuffix(issue.Plugin, "index.server.so") && issue.Reason != "built with go1.24.3, running go1.25.0" {
+			t.Errorf("expected go version mismatch for %s, got %q", issue.Plugin, issue.Reason)
+		}
+	}
+
+	env.GoVersion = "go1.24.3"
+	env.Modules["github.com/go-barry/barry"] = "v1.1.0"
+	issues = CheckPlugins(manifest, env)
+	if !strings.Contains(issues[0].Reason, "github.com/go-barry/barry v1.0.0, now v1.1.0") {
+		t.Errorf("expected module change, got %q", issues[0].Reason)
+	}
+}
+
+func TestValidatePlugins_MarksIncompatiblePluginsStale(t *testing.T) {
+	writePluginSite(t, map[string]string{
+		"routes/index.server.go": "package routes\n\nfunc HandleRequest() {}\n",
+		"routes/index.server.so": "so",
+	})
+	soPath := filepath.FromSlash("routes/index.server.so")
+	t.Cleanup(func() { pluginCache.Delete(soPath) })
+
+	if n := ValidatePlugins(nil); n != 1 {
+		t.Fatalf("expected missing manifest to skip 1 plugin, got %d", n)
+	}
+	if _, ok := cacheValue(soPath).(stalePlugin); !ok {
+		t.Fatal("expected plugin to be marked stale")
+	}
+	pluginCache.Delete(soPath)
+
+	hash, _ := PluginSourceHash("routes/index.server.go")
+	env := CurrentPluginEnv()
+	_ = WritePluginManifest(PluginManifestFile, &PluginManifest{
+		GoVersion: env.GoVersion,
+		Modules:   env.Modules,
+		Plugins:   map[string]PluginEntry{"routes/index.server.so": {Source: "routes/index.server.go", Hash: hash}},
+	})
+	if n := ValidatePlugins(nil); n != 0 {
+		t.Fatalf("expected compatible plugin to load, got %d issues", n)
+	}
+	if _, ok := pluginCache.Load(soPath); ok {
+		t.Error("expected compatible plugin to stay out of the cache")
+	}
+
+	_ = WritePluginManifest(PluginManifestFile, &PluginManifest{
+		GoVersion: "go0.0.1",
+		Plugins:   map[string]PluginEntry{"routes/index.server.so": {Source: "routes/index.server.go", Hash: hash}},
+	})
+	if n := ValidatePlugins(nil); n != 1 {
+		t.Fatalf("expected go version mismatch to skip plugin, got %d", n)
+	}
+	if _, ok := cacheValue(soPath).(stalePlugin); !ok {
+		t.Error("expected mismatched plugin to be marked stale")
+	}
+}
+
+func TestToolchainPluginEnv_ParsesGoOutput(t *testing.T) {
+	original := pluginEnvCommand
+	defer func() { pluginEnvCommand = original }()
+
+	pluginEnvCommand = func(name string, args ...string) *exec.Cmd {
+		if args[0] == "env" {
+			return exec.Command("echo", "go1.24.3")
+		}
+		return exec.Command("printf", "example.com/site \ngithub.com/go-barry/barry v1.0.0\ngithub.com/a/b v1.0.0 => ../b \n")
+	}
+
+	env, err := ToolchainPluginEnv(".")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if env.GoVersion != "go1.24.3" {
+		t.Errorf("unexpected go version %q", env.GoVersion)
+	}
+	if env.Modules["github.com/go-barry/barry"] != "v1.0.0" || env.Modules["github.com/a/b"] != "v1.0.0 => ../b" {
+		t.Errorf("unexpected modules %v", env.Modules)
+	}
+
+	pluginEnvCommand = func(string, ...string) *exec.Cmd { return exec.Command("false") }
+	if _, err := ToolchainPluginEnv("."); err == nil {
+		t.Error("expected error when go env fails")
+	}
+}
+
+func cacheValue(key string) interface{} {
+	v, _ := pluginCache.Load(key)
+	return v
+}
+
+func TestPluginDepsHash_FollowsLocalImports(t *testing.T) {
+	writePluginSite(t, map[string]string{
+		"go.mod":                      "module example.com/site\n",
+		"routes/blog/index.server.go": "package blog\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/site/lib\"\n)\n\nvar _ = fmt.Sprint(lib.Name)\n",
+		"lib/lib.go":                  "package lib\n\nimport \"example.com/site/lib/inner\"\n\nconst Name = inner.Name\n",
+		"lib/inner/inner.go":          "package inner\n\nconst Name = \"a\"\n",
+		"other/other.go":              "package other\n",
+	})
+
+	before, err := PluginDepsHash("routes/blog/index.server.go")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = os.WriteFile("other/other.go", []byte("package other\n\nconst X = 1\n"), 0644)
+	if after, _ := PluginDepsHash("routes/blog/index.server.go"); after != before {
+		t.Error("expected unrelated packages to be ignored")
+	}
+
+	_ = os.WriteFile("lib/inner/inner.go", []byte("package inner\n\nconst Name = \"b\"\n"), 0644)
+	if after, _ := PluginDepsHash("routes/blog/index.server.go"); after == before {
+		t.Error("expected hash to change when a transitive dependency changes")
+	}
+}
+
+func TestCheckPlugins_ReportsChangedDependencies(t *testing.T) {
+	writePluginSite(t, map[string]string{
+		"go.mod":                      "module example.com/site\n",
+		"routes/blog/index.server.go": "package blog\n\nimport \"example.com/site/lib\"\n\nvar _ = lib.Name\n",
+		"routes/blog/index.server.so": "so",
+		"lib/lib.go":                  "package lib\n\nconst Name = \"a\"\n",
+	})
+	hash, _ := PluginSourceHash("routes/blog/index.server.go")
+	deps, _ := PluginDepsHash("routes/blog/index.server.go")
+
+	manifest := &PluginManifest{
+		GoVersion: "go1.24.3",
+		Plugins: map[string]PluginEntry{
+			"routes/blog/index.server.so": {Source: "routes/blog/index.server.go", Hash: hash, Deps: deps},
+		},
+	}
+	env := PluginEnv{GoVersion: "go1.24.3"}
+
+	if issues := CheckPlugins(manifest, env); len(issues) != 0 {
+		t.Fatalf("expected up to date plugin to pass, got %v", issues)
+	}
+
+	_ = os.WriteFile("lib/lib.go", []byte("package lib\n\nconst Name = \"b\"\n"), 0644)
+	issues := CheckPlugins(manifest, env)
+	if len(issues) != 1 || issues[0].Reason != "dependencies changed since build" {
+		t.Errorf("expected changed dependencies, got %v", issues)
+	}
+}
@@ -0,0 +1,286 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const publicHeadersFile = "_headers"
+const publicRedirectsFile = "_redirects"
+
+type publicHeaderRule struct {
+	Pattern *regexp.Regexp
+	Headers http.Header
+}
+
+type publicRedirectRule struct {
+	Pattern     *regexp.Regexp
+	Names       []string
+	Destination string
+	Status      int
+}
+
+type PublicRules struct {
+	dir       string
+	fsys      fs.FS
+	watch     bool
+	logger    io.Writer
+	mu        sync.RWMutex
+	headers   []publicHeaderRule
+	redirects []publicRedirectRule
+	modTimes  [2]time.Time
+}
+
+func NewPublicRules(fsys fs.FS, dir string, env string, logger io.Writer) *PublicRules {
+	p := &PublicRules{dir: dir, fsys: fsys, watch: env == "dev", logger: logger}
+	p.load()
+	return p
+}
+
+func (p *PublicRules) Reload() {
+	p.load()
+}
+
+func (p *PublicRules) load() {
+	headersPath := filepath.Join(p.dir, publicHeadersFile)
+	redirectsPath := filepath.Join(p.dir, publicRedirectsFile)
+
+	var headers []publicHeaderRule
+	var redirects []publicRedirectRule
+
+	if data, err := siteReadFile(p.fsys, headersPath); err == nil {
+		headers = parsePublicHeaders(data, p.logger)
+	}
+	if data, err := siteReadFile(p.fsys, redirectsPath); err == nil {
+		redirects = parsePublicRedirects(data, p.logger)
+	}
+
+	p.mu.Lock()
+	p.headers = headers
+	p.redirects = redirects
+	p.modTimes = [2]time.Time{siteModTime(p.fsys, headersPath), siteModTime(p.fsys, redirectsPath)}
+	p.mu.Unlock()
+}
+
+func (p *PublicRules) reloadIfChanged() {
+	current := [2]time.Time{
+		siteModTime(p.fsys, filepath.Join(p.dir, publicHeadersFile)),
+		siteModTime(p.fsys, filepath.Join(p.dir, publicRedirectsFile)),
+	}
+
+	p.mu.RLock()
+	changed := current != p.modTimes
+	p.mu.RUnlock()
+
+	if changed {
+		p.load()
+	}
+}
+
+func modTime(path string) time.Time {
+	return siteModTime(nil, path)
+}
+
+func siteModTime(fsys fs.FS, path string) time.Time {
+	if info, err := siteStat(fsys, path); err == nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
+func (p *PublicRules) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if p.watch {
+			p.reloadIfChanged()
+		}
+
+		if name := strings.TrimPrefix(req.URL.Path, "/static/"); name == publicHeadersFile || name == publicRedirectsFile {
+			http.NotFound(w, req)
+			return
+		}
+
+		p.mu.RLock()
+		headers := p.matchHeaders(req.URL.Path)
+		target, status, matched := p.matchRedirect(req.URL.Path)
+		p.mu.RUnlock()
+
+		if matched && status != http.StatusOK {
+			for key, values := range headers {
+				w.Header()[key] = values
+			}
+			http.Redirect(w, req, withRedirectQuery(target, req), status)
+			return
+		}
+
+		if matched {
+			rewritten, path := rewriteRequest(req, target)
+			if rewritten == req {
+				rewritten = req.Clone(req.Context())
+			}
+			rewritten.URL.Path = "/" + path
+			rewritten.URL.RawPath = ""
+			req = rewritten
+		}
+
+		if len(headers) > 0 {
+			w = &headerHookWriter{ResponseWriter: w, before: func(h http.Header) {
+				for key, values := range headers {
+					h[key] = values
+				}
+			}}
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+func (p *PublicRules) matchHeaders(path string) http.Header {
+	result := http.Header{}
+	for _, rule := range p.headers {
+		if !rule.Pattern.MatchString(path) {
+			continue
+		}
+		for key, values := range rule.Headers {
+			result[key] = values
+		}
+	}
+	return result
+}
+
+func (p *PublicRules) matchRedirect(path string) (string, int, bool) {
+	for _, rule := range p.redirects {
+		matches := rule.Pattern.FindStringSubmatch(path)
+		if matches == nil {
+			continue
+		}
+
+		pairs := []string{}
+		for i, name := range rule.Names {
+			value := matches[i+1]
+			if rule.Status != http.StatusOK {
+				value = escapePathValue(value)
+			}
+			pairs = append(pairs, ":"+name, value)
+		}
+		target := strings.NewReplacer(pairs...).Replace(rule.Destination)
+		return sameSiteTarget(rule.Destination, target), rule.Status, true
+	}
+	return "", 0, false
+}
+
+func parsePublicHeaders(data []byte, out io.Writer) []publicHeaderRule {
+	rules := []publicHeaderRule{}
+	var current *publicHeaderRule
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if line[0] != ' ' && line[0] != '\t' {
+			pattern, _ := compilePublicGlob(trimmed)
+			rules = append(rules, publicHeaderRule{Pattern: pattern, Headers: http.Header{}})
+			current = &rules[len(rules)-1]
+			continue
+		}
+
+		name, value, ok := strings.Cut(trimmed, ":")
+		if current == nil || !ok || strings.TrimSpace(name) == "" {
+			logf(out, "⚠️ Skipping %s line %d: %q\n", publicHeadersFile, lineNo, trimmed)
+			continue
+		}
+		current.Headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	return rules
+}
+
+func parsePublicRedirects(data []byte, out io.Writer) []publicRedirectRule {
+	rules := []publicRedirectRule{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		trimmed := strings.TrimSpace(scanner.Text())
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		fields := strings.Fields(trimmed)
+		if len(fields) < 2 {
+			logf(out, "⚠️ Skipping %s line %d: missing destination\n", publicRedirectsFile, lineNo)
+			continue
+		}
+
+		status := http.StatusMovedPermanently
+		if len(fields) > 2 {
+			parsed, err := strconv.Atoi(strings.TrimSuffix(fields[2], "!"))
+			if err != nil || !validPublicRedirectStatus(parsed) {
+				logf(out, "⚠️ Skipping %s line %d: unsupported status %q\n", publicRedirectsFile, lineNo, fields[2])
+				continue
+			}
+			status = parsed
+		}
+
+		if status == http.StatusOK && strings.Contains(fields[1], "://") {
+			logf(out, "⚠️ Skipping %s line %d: rewrites must target a path within the site\n", publicRedirectsFile, lineNo)
+			continue
+		}
+
+		pattern, names := compilePublicGlob(fields[0])
+		rules = append(rules, publicRedirectRule{
+			Pattern:     pattern,
+			Names:       names,
+			Destination: fields[1],
+			Status:      status,
+		})
+	}
+
+	return rules
+}
+
+func validPublicRedirectStatus(status int) bool {
+	switch status {
+	case http.StatusOK, http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+func compilePublicGlob(glob string) (*regexp.Regexp, []string) {
+	names := []string{}
+	var pattern strings.Builder
+
+	parts := strings.Split(glob, "/")
+	for i, part := range parts {
+		if i > 0 {
+			pattern.WriteString("/")
+		}
+		switch {
+		case strings.HasPrefix(part, ":") && len(part) > 1:
+			names = append(names, part[1:])
+			pattern.WriteString("([^/]+)")
+		default:
+			for j, literal := range strings.Split(part, "*") {
+				if j > 0 {
+					names = append(names, "splat")
+					pattern.WriteString("(.*)")
+				}
+				pattern.WriteString(regexp.QuoteMeta(literal))
+			}
+		}
+	}
+
+	return regexp.MustCompile("^" + pattern.String() + "/?$"), names
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParsePublicHeaders(t *testing.T) {
+	rules := parsePublicHeaders([]byte(`# security
+/*
+  X-Frame-Options: DENY
+  Link: </a.css>; rel=preload
+  Link: </b.js>; rel=preload
+
+/static/*
+	Cache-Control: public, max-age=60
+not a header
+`), nil)
+
+	if len(rules) != 3 {
+		t.Fatalf("expected 3 rules, got %d", len(rules))
+	}
+	if rules[0].Headers.Get("X-Frame-Options") != "DENY" || len(rules[0].Headers["Link"]) != 2 {
+		t.Errorf("unexpected headers for /*: %v", rules[0].Headers)
+	}
+	if !rules[1].Pattern.MatchString("/static/css/app.css") || rules[1].Pattern.MatchString("/about") {
+		t.Errorf("unexpected match behaviour for /static/*")
+	}
+}
+
+func TestParsePublicRedirects(t *testing.T) {
+	rules := parsePublicRedirects([]byte(`
+# legacy
+/home              /
+/news/*            /blog/:splat
+/users/:id/posts   /u/:id          302
+/docs/*            /help/:splat    200
+/bad               /x              418
+/only-source
+/proxy/*           https://example.com/:splat 200
+`), nil)
+
+	if len(rules) != 4 {
+		t.Fatalf("expected 4 rules, got %d", len(rules))
+	}
+	if rules[0].Status != http.StatusMovedPermanently {
+		t.Errorf("expected default status 301, got %d", rules[0].Status)
+	}
+
+	p := &PublicRules{redirects: rules}
+	cases := []struct {
+		path   string
+		target string
+		status int
+	}{
+		{"/news/2020/launch", "/blog/2020/launch", 301},
+		{"/users/42/posts", "/u/42", 302},
+		{"/users/42/posts/", "/u/42", 302},
+		{"/docs/intro", "/help/intro", 200},
+	}
+	for _, c := range cases {
+		target, status, ok := p.matchRedirect(c.path)
+		if !ok || target != c.target || status != c.status {
+			t.Errorf("%s: expected %s %d, got %s %d (%v)", c.path, c.target, c.status, target, status, ok)
+		}
+	}
+
+	if _, _, ok := p.matchRedirect("/users/42"); ok {
+		t.Error("expected no match for /users/42")
+	}
+}
+
+func TestPublicRules_WrapAppliesHeadersAndRedirects(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "_headers"), []byte("/static/*\n  Cache-Control: public, max-age=60\n/*\n  X-Frame-Options: DENY\n"), 0644)
+	_ = os.WriteFile(filepath.Join(dir, "_redirects"), []byte("/old /new 308\n/alias/* /real/:splat 200\n"), 0644)
+
+	var seenPath string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenPath = r.URL.Path
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		w.Write([]byte("ok"))
+	})
+
+	handler := NewPublicRules(nil, dir, "prod", nil).Wrap(next)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/app.css", nil))
+	if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=60" {
+		t.Errorf("expected _headers to override Cache-Control, got %q", cc)
+	}
+	if rec.Header().Get("X-Frame-Options") != "DENY" {
+		t.Error("expected X-Frame-Options from /* rule")
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/old?x=1", nil))
+	if rec.Code != http.StatusPermanentRedirect || rec.Header().Get("Location") != "/new?x=1" {
+		t.Errorf("expected 308 to /new?x=1, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/alias/a/b", nil))
+	if seenPath != "/real/a/b" || rec.Header().Get("Location") != "" {
+		t.Errorf("expected internal rewrite to /real/a/b, got %q", seenPath)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/_redirects", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected rule files to be hidden, got %d", rec.Code)
+	}
+}
+
+func TestPublicRules_SplatRedirectDoesNotLeaveTheSite(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "_redirects"), []byte("/old/* /:splat 301\n"), 0644)
+
+	handler := NewPublicRules(nil, dir, "prod", nil).Wrap(http.NotFoundHandler())
+
+	for path, expected := range map[string]string{
+		"/old/%2Fevil.com": "/evil.com",
+		"/old//evil.com":   "/evil.com",
+		"/old/a%20b/c":     "/a%20b/c",
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if loc := rec.Header().Get("Location"); loc != expected {
+			t.Errorf("%s: expected Location %q, got %q", path, expected, loc)
+		}
+	}
+}
+
+func TestPublicRules_ReloadsInDev(t *testing.T) {
+	dir := t.TempDir()
+	redirects := filepath.Join(dir, "_redirects")
+	_ = os.WriteFile(redirects, []byte("/a /b\n"), 0644)
+
+	handler := NewPublicRules(nil, dir, "dev", nil).Wrap(http.NotFoundHandler())
+
+	_ = os.WriteFile(redirects, []byte("/a /c\n"), 0644)
+	future := time.Now().Add(time.Minute)
+	_ = os.Chtimes(redirects, future, future)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/a", nil))
+	if rec.Header().Get("Location") != "/c" {
+		t.Errorf("expected reloaded redirect to /c, got %q", rec.Header().Get("Location"))
+	}
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type HandlerFunc func(*http.Request, map[string]string) (interface{}, error)
+
+var registry = map[string]map[string]HandlerFunc{}
+var registryMu sync.RWMutex
+
+func Register(path string, handlers map[string]HandlerFunc) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	key := registryKey(path)
+	if registry[key] == nil {
+		registry[key] = map[string]HandlerFunc{}
+	}
+	for name, handler := range handlers {
+		registry[key][name] = handler
+	}
+}
+
+func registeredHandler(filePath string, req *http.Request) (HandlerFunc, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	if len(registry) == 0 {
+		return nil, false
+	}
+	handler, ok := registry[registryKey(filePath)][HandlerName(req)]
+	return handler, ok
+}
+
+func (h HandlerFunc) call(req *http.Request, params map[string]string) (map[string]interface{}, error) {
+	result, err := h(req, params)
+	if err != nil {
+		return nil, err
+	}
+	if res, ok := result.(responseMetaer); ok {
+		recordResponseMeta(req, res.ResponseMeta())
+	}
+	return resultMap(result)
+}
+
+func registryKey(path string) string {
+	if filepath.IsAbs(path) {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, path); err == nil {
+				path = rel
+			}
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(path))
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func resetRegistry(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		registryMu.Lock()
+		registry = map[string]map[string]HandlerFunc{}
+		registryMu.Unlock()
+	})
+}
+
+func TestExecuteServerFile_UsesRegisteredHandler(t *testing.T) {
+	resetRegistry(t)
+
+	originalPlugin := LoadPluginAndCallFunc
+	defer func() { LoadPluginAndCallFunc = originalPlugin }()
+	LoadPluginAndCallFunc = func(string, *http.Request, map[string]string) (map[string]interface{}, error) {
+		t.Fatal("expected registry to be used before plugins")
+		return nil, nil
+	}
+
+	Register("routes/blog/_slug/index.server.go", map[string]HandlerFunc{
+		"HandleRequest": func(r *http.Request, p map[string]string) (interface{}, error) {
+			return map[string]interface{}{"slug": p["slug"]}, nil
+		},
+	})
+
+	wd, _ := os.Getwd()
+	abs := filepath.Join(wd, "routes", "blog", "_slug", "index.server.go")
+
+	result, err := ExecuteServerFile(abs, httptest.NewRequest(http.MethodGet, "/blog/hi", nil), map[string]string{"slug": "hi"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["slug"] != "hi" {
+		t.Errorf("expected registered handler result, got %v", result)
+	}
+}
+
+func TestExecuteAPIFile_UsesRegisteredMethodHandler(t *testing.T) {
+	resetRegistry(t)
+
+	Register("api/users/post.go", map[string]HandlerFunc{
+		"HandlePost": func(r *http.Request, p map[string]string) (interface{}, error) {
+			return map[string]interface{}{"created": true}, nil
+		},
+	})
+
+	req := withHandlerName(httptest.NewRequest(http.MethodPost, "/api/users", nil), "HandlePost")
+	out, err := ExecuteAPIFile("./api/users/post.go", req, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(out), `"created":true`) {
+		t.Errorf("expected registered handler output, got %s", out)
+	}
+}
+
+func TestExecuteAPIFile_RegisteredResponseRecordsMeta(t *testing.T) {
+	resetRegistry(t)
+
+	Register("api/users/post.go", map[string]HandlerFunc{
+		"HandleRequest": func(r *http.Request, p map[string]string) (interface{}, error) {
+			return Response{Status: http.StatusCreated, Data: map[string]interface{}{"id": 7}}, nil
+		},
+	})
+
+	req, slot := withResponseMeta(httptest.NewRequest(http.MethodPost, "/api/users", nil))
+	out, err := ExecuteAPIFile("./api/users/post.go", req, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out) != `{"id":7}` {
+		t.Errorf("expected only data in the body, got %s", out)
+	}
+	if slot.get().Status != http.StatusCreated {
+		t.Errorf("expected status to be recorded, got %+v", slot.get())
+	}
+}
+
+func TestExecuteServerFile_UnregisteredHandlerFallsBack(t *testing.T) {
+	resetRegistry(t)
+
+	Register("routes/home/index.server.go", map[string]HandlerFunc{
+		"HandlePost": func(*http.Request, map[string]string) (interface{}, error) { return nil, nil },
+	})
+
+	originalPlugin := LoadPluginAndCallFunc
+	defer func() { LoadPluginAndCallFunc = originalPlugin }()
+	LoadPluginAndCallFunc = func(string, *http.Request, map[string]string) (map[string]interface{}, error) {
+		return map[string]interface{}{"from": "plugin"}, nil
+	}
+
+	result, err := ExecuteServerFile("routes/home/index.server.go", httptest.NewRequest(http.MethodGet, "/", nil), nil)
+	if err != nil || result["from"] != "plugin" {
+		t.Errorf("expected fallback to plugin, got %v, %v", result, err)
+	}
+}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+type Response struct {
+	Status   int
+	Headers  map[string]string
+	Cookies  []*http.Cookie
+	Redirect string
+	Params   map[string]string
+	Data     map[string]interface{}
+}
+
+func (res Response) Map() map[string]interface{} {
+	out := make(map[string]interface{}, len(res.Data))
+	for k, v := range res.Data {
+		out[k] = v
+	}
+	return out
+}
+
+func (res Response) ResponseMeta() ResponseMeta {
+	meta := ResponseMeta{
+		Status:   res.Status,
+		Headers:  res.Headers,
+		Redirect: res.Redirect,
+		Params:   res.Params,
+	}
+	for _, c := range res.Cookies {
+		meta.Cookies = append(meta.Cookies, c.String())
+	}
+	return meta
+}
+
+type ResponseError struct {
+	Status   int
+	Message  string
+	Location string
+}
+
+func (e *ResponseError) Error() string {
+	if e.Location != "" {
+		return fmt.Sprintf("redirect %d to %s", e.Status, e.Location)
+	}
+	return e.Message
+}
+
+func (e *ResponseError) StatusCode() int {
+	return e.Status
+}
+
+func (e *ResponseError) RedirectLocation() string {
+	return e.Location
+}
+
+func Redirect(url string, code int) error {
+	if code < 300 || code > 399 {
+		code = http.StatusFound
+	}
+	return &ResponseError{Status: code, Location: url}
+}
+
+func HTTPError(status int, msg string) error {
+	if msg == "" {
+		msg = http.StatusText(status)
+	}
+	return &ResponseError{Status: status, Message: msg}
+}
+
+func asResponseError(err error) (*ResponseError, bool) {
+	var re *ResponseError
+	if errors.As(err, &re) && re.Status != 0 {
+		return re, true
+	}
+	return nil, false
+}
+
+type ResponseMeta struct {
+	Status   int
+	Headers  map[string]string
+	Cookies  []string
+	Redirect string
+	Params   map[string]string
+}
+
+type responseMetaer interface {
+	ResponseMeta() ResponseMeta
+}
+
+func (m ResponseMeta) empty() bool {
+	return m.Status == 0 && len(m.Headers) == 0 && len(m.Cookies) == 0 && m.Redirect == ""
+}
+
+func (m ResponseMeta) apply(w http.ResponseWriter) {
+	for k, v := range m.Headers {
+		w.Header().Set(k, v)
+	}
+	for _, cookie := range m.Cookies {
+		w.Header().Add("Set-Cookie", cookie)
+	}
+}
+
+func (m ResponseMeta) redirectStatus() int {
+	if m.Status < 300 || m.Status > 399 {
+		return http.StatusFound
+	}
+	return m.Status
+}
+
+type responseMetaKey struct{}
+
+type responseMetaSlot struct {
+	mu   sync.Mutex
+	meta ResponseMeta
+}
+
+func withResponseMeta(req *http.Request) (*http.Request, *responseMetaSlot) {
+	slot := &responseMetaSlot{}
+	return req.WithContext(context.WithValue(req.Context(), responseMetaKey{}, slot)), slot
+}
+
+func recordResponseMeta(req *http.Request, meta ResponseMeta) {
+	if slot, ok := req.Context().Value(responseMetaKey{}).(*responseMetaSlot); ok {
+		slot.mu.Lock()
+		slot.meta = meta
+		slot.mu.Unlock()
+	}
+}
+
+func (s *responseMetaSlot) get() ResponseMeta {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.meta
+}
+
+func (r *Router) handleExecError(w http.ResponseWriter, req *http.Request, err error, isAPI bool, label string) {
+	if re, ok := asResponseError(err); ok {
+		switch {
+		case re.Location != "":
+			http.Redirect(w, req, re.Location, re.Status)
+		case isAPI:
+			http.Error(w, re.Message, re.Status)
+		default:
+			r.renderErrorPage(w, req, re.Status, re.Message)
+		}
+		return
+	}
+
+	switch {
+	case IsNotFoundError(err):
+		if isAPI {
+			http.Error(w, "Not Found", http.StatusNotFound)
+		} else {
+			r.renderErrorPage(w, req, http.StatusNotFound, "Page not found")
+		}
+	case IsTimeoutError(err):
+		r.logf("⏱️ Timed out: %s\n", req.URL.Path)
+		if isAPI {
+			http.Error(w, "Gateway Timeout", http.StatusGatewayTimeout)
+		} else {
+			r.renderErrorPage(w, req, http.StatusGatewayTimeout, "Gateway Timeout")
+		}
+	case errors.Is(err, context.Canceled):
+		return
+	default:
+		http.Error(w, label+err.Error(), http.StatusInternalServerError)
+	}
+}
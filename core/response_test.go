@@ -0,0 +1,211 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestResponse_KeepsMetaOutOfData(t *testing.T) {
+	res := Response{
+		Status:  http.StatusCreated,
+		Headers: map[string]string{"X-Test": "1"},
+		Cookies: []*http.Cookie{{Name: "session", Value: "abc", Path: "/"}},
+		Data:    map[string]interface{}{"title": "Hi"},
+	}
+
+	if data := res.Map(); !reflect.DeepEqual(data, map[string]interface{}{"title": "Hi"}) {
+		t.Errorf("unexpected data: %v", data)
+	}
+
+	meta := res.ResponseMeta()
+	if meta.Status != http.StatusCreated || meta.Headers["X-Test"] != "1" {
+		t.Errorf("unexpected meta: %+v", meta)
+	}
+	if len(meta.Cookies) != 1 || meta.Cookies[0] != "session=abc; Path=/" {
+		t.Errorf("unexpected cookies: %v", meta.Cookies)
+	}
+	if meta.empty() {
+		t.Error("expected meta not to be empty")
+	}
+}
+
+func TestRecordResponseMeta_WithoutSlotIsIgnored(t *testing.T) {
+	recordResponseMeta(httptest.NewRequest(http.MethodGet, "/", nil), ResponseMeta{Status: http.StatusTeapot})
+
+	req, slot := withResponseMeta(httptest.NewRequest(http.MethodGet, "/", nil))
+	recordResponseMeta(withHandlerName(req, "HandlePost"), ResponseMeta{Status: http.StatusTeapot})
+	if slot.get().Status != http.StatusTeapot {
+		t.Errorf("expected meta to reach the slot, got %+v", slot.get())
+	}
+}
+
+func TestRedirectAndHTTPErrorDefaults(t *testing.T) {
+	re, ok := asResponseError(Redirect("/login", 0))
+	if !ok || re.Status != http.StatusFound || re.Location != "/login" {
+		t.Errorf("unexpected redirect: %+v", re)
+	}
+
+	re, ok = asResponseError(HTTPError(http.StatusForbidden, ""))
+	if !ok || re.Status != http.StatusForbidden || re.Message != "Forbidden" {
+		t.Errorf("unexpected http error: %+v", re)
+	}
+
+	if _, ok := asResponseError(ErrNotFound); ok {
+		t.Error("expected ErrNotFound not to be a response error")
+	}
+}
+
+type serverStub func(path string, req *http.Request, params map[string]string) (map[string]interface{}, error)
+
+func stubSite(t *testing.T, files map[string]string, stub serverStub) *[]string {
+	t.Helper()
+	setupExecSite(t)
+
+	for path, content := range files {
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		_ = os.WriteFile(path, []byte(content), 0644)
+	}
+
+	var mu sync.Mutex
+	calls := []string{}
+	original := ExecuteServerFile
+	ExecuteServerFile = func(path string, req *http.Request, params map[string]string) (map[string]interface{}, error) {
+		call := filepath.ToSlash(path) + ":" + HandlerName(req)
+		if len(params) > 0 {
+			call += "?" + paramsKey(params)
+		}
+		mu.Lock()
+		calls = append(calls, call)
+		mu.Unlock()
+		return stub(path, req, params)
+	}
+	t.Cleanup(func() { ExecuteServerFile = original })
+	return &calls
+}
+
+func TestServeHTTP_PageResponseSetsStatusHeadersAndCookies(t *testing.T) {
+	stubSite(t, nil, func(_ string, req *http.Request, _ map[string]string) (map[string]interface{}, error) {
+		recordResponseMeta(req, Response{
+			Status:  http.StatusAccepted,
+			Headers: map[string]string{"X-Handler": "yes"},
+			Cookies: []*http.Cookie{{Name: "seen", Value: "1"}},
+		}.ResponseMeta())
+		return map[string]interface{}{"_status": 500}, nil
+	})
+
+	router := NewRouter(Config{OutputDir: "cache"}, RuntimeContext{Env: "prod"})
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("expected 202, got %d", rec.Code)
+	}
+	if rec.Header().Get("X-Handler") != "yes" || rec.Header().Get("Set-Cookie") != "seen=1" {
+		t.Errorf("unexpected headers: %v", rec.Header())
+	}
+	if !strings.Contains(rec.Body.String(), "slow") {
+		t.Errorf("expected page to render, got %q", rec.Body.String())
+	}
+}
+
+func TestServeHTTP_PageHandlerErrors(t *testing.T) {
+
+	cases := []struct {
+		err      error
+		status   int
+		location string
+		body     string
+	}{
+		{Redirect("/login", http.StatusSeeOther), http.StatusSeeOther, "/login", ""},
+		{HTTPError(http.StatusForbidden, "Members only"), http.StatusForbidden, "", "<h1>Error 403: Members only</h1>"},
+	}
+
+	for _, tc := range cases {
+		stubSite(t, nil, func(string, *http.Request, map[string]string) (map[string]interface{}, error) { return nil, tc.err })
+
+		router := NewRouter(Config{OutputDir: "cache"}, RuntimeContext{Env: "prod"})
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+		if rec.Code != tc.status {
+			t.Errorf("%v: expected %d, got %d", tc.err, tc.status, rec.Code)
+		}
+		if rec.Header().Get("Location") != tc.location {
+			t.Errorf("%v: expected Location %q, got %q", tc.err, tc.location, rec.Header().Get("Location"))
+		}
+		if !strings.Contains(rec.Body.String(), tc.body) {
+			t.Errorf("%v: expected body to contain %q, got %q", tc.err, tc.body, rec.Body.String())
+		}
+	}
+}
+
+func TestHandleAPI_AppliesResponseMeta(t *testing.T) {
+	original := ExecuteAPIFile
+	defer func() { ExecuteAPIFile = original }()
+
+	ExecuteAPIFile = func(_ string, req *http.Request, _ map[string]string) ([]byte, error) {
+		recordResponseMeta(req, ResponseMeta{
+			Status:  http.StatusCreated,
+			Headers: map[string]string{"Location": "/api/users/7"},
+		})
+		return []byte(`{"id":7}`), nil
+	}
+
+	r := &Router{}
+	rec := httptest.NewRecorder()
+	r.handleAPI(rec, httptest.NewRequest(http.MethodPost, "/api/users", nil), ApiRoute{Method: "ANY"}, nil)
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d", rec.Code)
+	}
+	if rec.Header().Get("Location") != "/api/users/7" {
+		t.Errorf("expected Location header, got %v", rec.Header())
+	}
+	if rec.Body.String() != `{"id":7}` {
+		t.Errorf("unexpected body %s", rec.Body.String())
+	}
+}
+
+func TestHandleAPI_LeavesBodyUntouched(t *testing.T) {
+	original := ExecuteAPIFile
+	defer func() { ExecuteAPIFile = original }()
+
+	body := `{"_status": 201, "_redirect": "/elsewhere", "n": 1.50}`
+	ExecuteAPIFile = func(_ string, _ *http.Request, _ map[string]string) ([]byte, error) {
+		return []byte(body), nil
+	}
+
+	r := &Router{}
+	rec := httptest.NewRecorder()
+	r.handleAPI(rec, httptest.NewRequest(http.MethodGet, "/api/users", nil), ApiRoute{Method: "ANY"}, nil)
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Location") != "" {
+		t.Errorf("expected user keys not to act as meta, got %d %v", rec.Code, rec.Header())
+	}
+	if rec.Body.String() != body {
+		t.Errorf("expected body byte-for-byte, got %s", rec.Body.String())
+	}
+}
+
+func TestHandleAPI_HTTPError(t *testing.T) {
+	original := ExecuteAPIFile
+	defer func() { ExecuteAPIFile = original }()
+
+	ExecuteAPIFile = func(_ string, _ *http.Request, _ map[string]string) ([]byte, error) {
+		return nil, HTTPError(http.StatusConflict, "already exists")
+	}
+
+	r := &Router{}
+	rec := httptest.NewRecorder()
+	r.handleAPI(rec, httptest.NewRequest(http.MethodPost, "/api/users", nil), ApiRoute{Method: "ANY"}, nil)
+
+	if rec.Code != http.StatusConflict || strings.TrimSpace(rec.Body.String()) != "already exists" {
+		t.Errorf("expected 409 already exists, got %d %q", rec.Code, rec.Body.String())
+	}
+}
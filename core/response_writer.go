@@ -0,0 +1,50 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+type headerHookWriter struct {
+	http.ResponseWriter
+	before      func(http.Header)
+	wroteHeader bool
+}
+
+func (w *headerHookWriter) runHook() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.before(w.ResponseWriter.Header())
+}
+
+func (w *headerHookWriter) WriteHeader(code int) {
+	w.runHook()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *headerHookWriter) Write(data []byte) (int, error) {
+	w.runHook()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *headerHookWriter) Flush() {
+	w.runHook()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *headerHookWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("hijacking not supported")
+}
+
+func (w *headerHookWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,215 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+
+	json "github.com/segmentio/encoding/json"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+var requestType = reflect.TypeOf((*http.Request)(nil))
+var paramsType = reflect.TypeOf(map[string]string(nil))
+
+type resultMapper interface {
+	Map() map[string]interface{}
+}
+
+func resultMap(v interface{}) (map[string]interface{}, error) {
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return typedMap(val), nil
+	case resultMapper:
+		return typedMap(val.Map()), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		out := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			out[iter.Key().String()] = typedValue(iter.Value().Interface())
+		}
+		return out, nil
+	case reflect.Struct:
+		out := map[string]interface{}{}
+		structFields(rv, out)
+		return out, nil
+	}
+
+	return nil, fmt.Errorf("unsupported handler result type %T", v)
+}
+
+func structFields(rv reflect.Value, out map[string]interface{}) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := rv.Field(i)
+
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			embedded := value
+			if embedded.Kind() == reflect.Pointer {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				structFields(embedded, out)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name := fieldName(field); name != "" {
+			out[name] = typedValue(value.Interface())
+		}
+	}
+}
+
+func fieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+func typedMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = typedValue(v)
+	}
+	return out
+}
+
+func typedValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case int:
+		return int64(val)
+	case int8:
+		return int64(val)
+	case int16:
+		return int64(val)
+	case int32:
+		return int64(val)
+	case uint:
+		return uintValue(uint64(val))
+	case uint8:
+		return int64(val)
+	case uint16:
+		return int64(val)
+	case uint32:
+		return int64(val)
+	case uint64:
+		return uintValue(val)
+	case float32:
+		return float64(val)
+	case map[string]interface{}:
+		return typedMap(val)
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = typedValue(item)
+		}
+		return out
+	}
+	return v
+}
+
+func uintValue(v uint64) interface{} {
+	if v > math.MaxInt64 {
+		return float64(v)
+	}
+	return int64(v)
+}
+
+func handlerFunc(sym interface{}) (HandlerFunc, bool) {
+	switch fn := sym.(type) {
+	case func(*http.Request, map[string]string) (map[string]interface{}, error):
+		return func(r *http.Request, p map[string]string) (interface{}, error) {
+			return fn(r, p)
+		}, true
+	case func(*http.Request, map[string]string) (interface{}, error):
+		return fn, true
+	case HandlerFunc:
+		return fn, true
+	}
+
+	fn := reflect.ValueOf(sym)
+	if fn.Kind() != reflect.Func {
+		return nil, false
+	}
+	t := fn.Type()
+	if t.NumIn() != 2 || t.In(0) != requestType || t.In(1) != paramsType {
+		return nil, false
+	}
+	if t.NumOut() != 2 || t.Out(1) != errorType {
+		return nil, false
+	}
+
+	return func(r *http.Request, p map[string]string) (interface{}, error) {
+		out := fn.Call([]reflect.Value{reflect.ValueOf(r), reflect.ValueOf(p)})
+		err, _ := out[1].Interface().(error)
+		return out[0].Interface(), err
+	}, true
+}
+
+func decodeResult(data []byte) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var result map[string]interface{}
+	if err := dec.Decode(&result); err != nil {
+		return nil, err
+	}
+	return normalizeNumbers(result).(map[string]interface{}), nil
+}
+
+func normalizeNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+		return val.String()
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = normalizeNumbers(item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = normalizeNumbers(item)
+		}
+		return val
+	}
+	return v
+}
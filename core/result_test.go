@@ -0,0 +1,157 @@
+package core
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type resultBase struct {
+	ID int
+}
+
+type resultPage struct {
+	*resultBase
+	Title   string
+	Price   int `json:"price,omitempty"`
+	Skipped int `json:"-"`
+	hidden  string
+}
+
+func TestResultMap(t *testing.T) {
+	page := resultPage{resultBase: &resultBase{ID: 3}, Title: "Hat", Price: 3000000, Skipped: 1, hidden: "x"}
+
+	for _, v := range []interface{}{page, &page} {
+		result, err := resultMap(v)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(result) != 3 || result["ID"] != int64(3) || result["Title"] != "Hat" || result["price"] != int64(3000000) {
+			t.Errorf("unexpected struct result %#v", result)
+		}
+	}
+
+	result, err := resultMap(map[string]int{"count": 2})
+	if err != nil || result["count"] != int64(2) {
+		t.Errorf("expected typed map to convert, got %#v, %v", result, err)
+	}
+
+	result, err = resultMap(Response{Status: http.StatusCreated, Data: map[string]interface{}{"ok": true}})
+	if err != nil || len(result) != 1 || result["ok"] != true {
+		t.Errorf("expected Response to use Map(), got %#v, %v", result, err)
+	}
+
+	var nilPage *resultPage
+	if result, err := resultMap(nilPage); result != nil || err != nil {
+		t.Errorf("expected nil pointer to produce no result, got %#v, %v", result, err)
+	}
+
+	if _, err := resultMap([]int{1}); err == nil || !strings.Contains(err.Error(), "unsupported handler result type []int") {
+		t.Errorf("expected unsupported type error, got %v", err)
+	}
+}
+
+func TestResultMap_KeepsNestedTypes(t *testing.T) {
+	type author struct {
+		Name string
+	}
+	type post struct {
+		Author  author
+		Created time.Time `json:"created"`
+	}
+
+	created := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	result, err := resultMap(post{Author: author{Name: "Ann"}, Created: created})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tmpl := template.Must(template.New("page").Parse(`{{ .Author.Name }} {{ .created.Year }}`))
+	var out strings.Builder
+	if err := tmpl.Execute(&out, result); err != nil {
+		t.Fatalf("expected typed fields to reach the template, got %v", err)
+	}
+	if out.String() != "Ann 2024" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
+
+func TestResultMap_NormalizesNumbersLikeSubprocess(t *testing.T) {
+	data := map[string]interface{}{"count": 2, "ratio": float32(0.5), "items": []interface{}{uint8(1)}}
+
+	result, err := resultMap(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	decoded, _ := decodeResult([]byte(`{"count":2,"ratio":0.5,"items":[1]}`))
+	if !reflect.DeepEqual(result, decoded) {
+		t.Errorf("expected in-process numbers to match subprocess numbers, got %#v and %#v", result, decoded)
+	}
+	if data["count"] != 2 {
+		t.Errorf("expected handler map to be left alone, got %#v", data["count"])
+	}
+}
+
+func TestDecodeResult_KeepsIntegers(t *testing.T) {
+	result, err := decodeResult([]byte(`{"price":3000000,"rating":4.5,"big":1e400,"items":[{"n":1}]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result["price"] != int64(3000000) {
+		t.Errorf("expected int64, got %#v", result["price"])
+	}
+	if result["rating"] != 4.5 {
+		t.Errorf("expected float64, got %#v", result["rating"])
+	}
+	if result["big"] != "1e400" {
+		t.Errorf("expected out of range number to stay a string, got %#v", result["big"])
+	}
+	items := result["items"].([]interface{})
+	if items[0].(map[string]interface{})["n"] != int64(1) {
+		t.Errorf("expected nested integers, got %#v", items)
+	}
+
+	if _, err := decodeResult([]byte(`{`)); err == nil {
+		t.Error("expected decode error")
+	}
+}
+
+func TestHandlerFunc_AcceptsTypedHandlers(t *testing.T) {
+	typed := func(r *http.Request, p map[string]string) (resultPage, error) {
+		return resultPage{Title: p["title"], Price: 10}, nil
+	}
+	handler, ok := handlerFunc(typed)
+	if !ok {
+		t.Fatal("expected typed handler to be accepted")
+	}
+
+	result, err := handler.call(httptest.NewRequest(http.MethodGet, "/", nil), map[string]string{"title": "Hat"})
+	if err != nil || result["Title"] != "Hat" || result["price"] != int64(10) {
+		t.Errorf("unexpected result %#v, %v", result, err)
+	}
+
+	failing := func(*http.Request, map[string]string) (*resultPage, error) {
+		return nil, errors.New("boom")
+	}
+	handler, _ = handlerFunc(failing)
+	if _, err := handler.call(nil, nil); err == nil || err.Error() != "boom" {
+		t.Errorf("expected handler error, got %v", err)
+	}
+
+	for _, bad := range []interface{}{
+		"HandleRequest",
+		func(*http.Request) (resultPage, error) { return resultPage{}, nil },
+		func(*http.Request, map[string]string) (resultPage, bool) { return resultPage{}, true },
+	} {
+		if _, ok := handlerFunc(bad); ok {
+			t.Errorf("expected %T to be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type segmentKind int
+
+const (
+	segmentStatic segmentKind = iota
+	segmentParam
+	segmentOptional
+	segmentCatchAll
+	segmentOptionalCatchAll
+)
+
+type routeSegment struct {
+	Kind   segmentKind
+	Value  string
+	RawKey string
+	Ext    string
+}
+
+func (s routeSegment) isCatchAll() bool {
+	return s.Kind == segmentCatchAll || s.Kind == segmentOptionalCatchAll
+}
+
+func (s routeSegment) isOptional() bool {
+	return s.Kind == segmentOptional || s.Kind == segmentOptionalCatchAll
+}
+
+func parseRouteSegment(part string) routeSegment {
+	if !strings.HasPrefix(part, "_") {
+		return routeSegment{Kind: segmentStatic, Value: part}
+	}
+
+	rawKey := part[1:]
+	name := rawKey
+	kind := segmentParam
+
+	if strings.HasPrefix(name, "[") {
+		if end := strings.Index(name, "]"); end > 0 {
+			name = name[1:end] + name[end+1:]
+			kind = segmentOptional
+		}
+	} else if strings.HasPrefix(name, "_") {
+		name = name[1:]
+		kind = segmentOptional
+	}
+
+	if strings.HasPrefix(name, "...") {
+		name = strings.TrimPrefix(name, "...")
+		if kind == segmentOptional {
+			kind = segmentOptionalCatchAll
+		} else {
+			kind = segmentCatchAll
+		}
+	}
+
+	ext := filepath.Ext(name)
+
+	return routeSegment{
+		Kind:   kind,
+		Value:  strings.TrimSuffix(name, ext),
+		RawKey: rawKey,
+		Ext:    ext,
+	}
+}
+
+func parseRouteSegments(rel string) ([]routeSegment, error) {
+	trimmed := strings.Trim(filepath.ToSlash(rel), "/")
+	if trimmed == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(trimmed, "/")
+	segments := make([]routeSegment, 0, len(parts))
+	for i, part := range parts {
+		seg := parseRouteSegment(part)
+		if seg.Kind != segmentStatic && seg.Value == "" {
+			return nil, fmt.Errorf("empty parameter name in segment %q", part)
+		}
+		if seg.isCatchAll() && i != len(parts)-1 {
+			return nil, fmt.Errorf("catch-all segment %q must be the last segment", part)
+		}
+		segments = append(segments, seg)
+	}
+
+	return segments, nil
+}
+
+func CheckImportable(path string) error {
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if !strings.ContainsAny(part, "[]") {
+			continue
+		}
+		if strings.HasPrefix(part, "_[") {
+			return fmt.Errorf("server files under %q can't be imported by Go, rename it to %q", part, "__"+strings.NewReplacer("[", "", "]", "").Replace(part[1:]))
+		}
+		return fmt.Errorf("server files under %q can't be imported by Go", part)
+	}
+	return nil
+}
+
+func compileRoutePattern(segments []routeSegment) (*regexp.Regexp, []string, []string) {
+	paramKeys := []string{}
+	paramRawKeys := []string{}
+
+	var pattern strings.Builder
+	needSep := false
+
+	for _, seg := range segments {
+		var expr string
+		switch seg.Kind {
+		case segmentStatic:
+			expr = regexp.QuoteMeta(seg.Value)
+		case segmentCatchAll, segmentOptionalCatchAll:
+			expr = "(.+)"
+		default:
+			expr = "([^/]+)"
+		}
+
+		if seg.Kind != segmentStatic {
+			paramKeys = append(paramKeys, seg.Value)
+			paramRawKeys = append(paramRawKeys, seg.RawKey)
+		}
+
+		switch {
+		case seg.isOptional() && needSep:
+			pattern.WriteString("(?:/" + expr + ")?")
+		case seg.isOptional():
+			pattern.WriteString("(?:" + expr + "(?:/|$))?")
+		case needSep:
+			pattern.WriteString("/" + expr)
+			needSep = true
+		default:
+			pattern.WriteString(expr)
+			needSep = true
+		}
+	}
+
+	return regexp.MustCompile("^" + pattern.String() + "$"), paramKeys, paramRawKeys
+}
+
+func extractParams(paramKeys, paramRawKeys []string, matches []string) map[string]string {
+	params := map[string]string{}
+	for i, key := range paramKeys {
+		rawKey := paramRawKeys[i]
+		seg := parseRouteSegment("_" + rawKey)
+		value := ""
+		if i+1 < len(matches) {
+			value = matches[i+1]
+		}
+
+		if seg.Ext != "" && strings.HasSuffix(value, seg.Ext) {
+			value = strings.TrimSuffix(value, seg.Ext)
+		}
+
+		params[key] = value
+
+		if seg.isCatchAll() && value != "" {
+			for j, part := range strings.Split(value, "/") {
+				params[key+"."+strconv.Itoa(j)] = part
+			}
+		}
+	}
+	return params
+}
+
+func SplitParam(params map[string]string, key string) []string {
+	parts := []string{}
+	for i := 0; ; i++ {
+		part, ok := params[key+"."+strconv.Itoa(i)]
+		if !ok {
+			return parts
+		}
+		parts = append(parts, part)
+	}
+}
@@ -0,0 +1,210 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRouteSegment_Kinds(t *testing.T) {
+	cases := []struct {
+		part  string
+		kind  segmentKind
+		value string
+		ext   string
+	}{
+		{"about", segmentStatic, "about", ""},
+		{"_id", segmentParam, "id", ""},
+		{"_id.html", segmentParam, "id", ".html"},
+		{"_[page]", segmentOptional, "page", ""},
+		{"_...slug", segmentCatchAll, "slug", ""},
+		{"_...slug.html", segmentCatchAll, "slug", ".html"},
+		{"_[...slug]", segmentOptionalCatchAll, "slug", ""},
+		{"__page", segmentOptional, "page", ""},
+		{"__...slug", segmentOptionalCatchAll, "slug", ""},
+	}
+
+	for _, tc := range cases {
+		seg := parseRouteSegment(tc.part)
+		if seg.Kind != tc.kind || seg.Value != tc.value || seg.Ext != tc.ext {
+			t.Errorf("%s: expected (%d, %q, %q), got (%d, %q, %q)", tc.part, tc.kind, tc.value, tc.ext, seg.Kind, seg.Value, seg.Ext)
+		}
+	}
+}
+
+func TestParseRouteSegments_CatchAllMustBeLast(t *testing.T) {
+	if _, err := parseRouteSegments("/docs/_...slug/edit"); err == nil {
+		t.Error("expected error for catch-all that is not the last segment")
+	}
+}
+
+func TestCompileRoutePattern_Matches(t *testing.T) {
+	cases := []struct {
+		rel     string
+		match   []string
+		noMatch []string
+	}{
+		{"/docs/_...slug", []string{"docs/a", "docs/a/b/c"}, []string{"docs", "other/a"}},
+		{"/docs/_[...slug]", []string{"docs", "docs/a/b"}, []string{"other"}},
+		{"/blog/_[page]", []string{"blog", "blog/2"}, []string{"blog/2/3"}},
+		{"/_[lang]/about", []string{"about", "en/about"}, []string{"en/fr/about"}},
+		{"/blog/__page", []string{"blog", "blog/2"}, []string{"blog/2/3"}},
+	}
+
+	for _, tc := range cases {
+		segments, err := parseRouteSegments(tc.rel)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.rel, err)
+		}
+		regex, _, _ := compileRoutePattern(segments)
+		for _, path := range tc.match {
+			if !regex.MatchString(path) {
+				t.Errorf("%s: expected %q to match %s", tc.rel, path, regex)
+			}
+		}
+		for _, path := range tc.noMatch {
+			if regex.MatchString(path) {
+				t.Errorf("%s: expected %q not to match %s", tc.rel, path, regex)
+			}
+		}
+	}
+}
+
+func TestExtractParams_CatchAllJoinedAndSplit(t *testing.T) {
+	segments, _ := parseRouteSegments("/docs/_...slug.html")
+	regex, keys, rawKeys := compileRoutePattern(segments)
+
+	params := extractParams(keys, rawKeys, regex.FindStringSubmatch("docs/a/b/c.html"))
+
+	if params["slug"] != "a/b/c" {
+		t.Errorf("expected joined value a/b/c, got %q", params["slug"])
+	}
+	if got := SplitParam(params, "slug"); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("expected split parts [a b c], got %v", got)
+	}
+}
+
+func TestExtractParams_OptionalMissing(t *testing.T) {
+	segments, _ := parseRouteSegments("/blog/_[page]")
+	regex, keys, rawKeys := compileRoutePattern(segments)
+
+	params := extractParams(keys, rawKeys, regex.FindStringSubmatch("blog"))
+
+	if value, ok := params["page"]; !ok || value != "" {
+		t.Errorf("expected empty page param, got %q (present: %v)", value, ok)
+	}
+	if got := SplitParam(params, "page"); len(got) != 0 {
+		t.Errorf("expected no split parts, got %v", got)
+	}
+}
+
+func TestRouter_ServesCatchAllRoute(t *testing.T) {
+	t.Cleanup(cleanupTestArtifacts)
+
+	_ = os.MkdirAll("routes/docs/_...slug", 0755)
+	_ = os.WriteFile("routes/docs/_...slug/index.html", []byte(`<!-- layout: layout.html -->
+{{ define "content" }}<h1>Doc: {{ .slug }}</h1>{{ end }}`), 0644)
+	_ = os.WriteFile("routes/docs/_...slug/index.server.go", []byte(""), 0644)
+	_ = os.WriteFile("layout.html", []byte(`{{ define "layout" }}<html><body>{{ template "content" . }}</body></html>{{ end }}`), 0644)
+	_ = os.MkdirAll("components", 0755)
+
+	var captured map[string]string
+	original := ExecuteServerFile
+	ExecuteServerFile = func(_ string, _ *http.Request, params map[string]string) (map[string]interface{}, error) {
+		captured = params
+		return map[string]interface{}{"slug": params["slug"]}, nil
+	}
+	defer func() { ExecuteServerFile = original }()
+
+	router := NewRouter(Config{OutputDir: t.TempDir()}, RuntimeContext{Env: "dev"})
+
+	req := httptest.NewRequest(http.MethodGet, "/docs/guide/routing/params", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 OK, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "Doc: guide/routing/params") {
+		t.Errorf("expected joined slug in body, got: %s", rec.Body.String())
+	}
+	if captured["slug.2"] != "params" {
+		t.Errorf("expected split param slug.2, got %v", captured)
+	}
+}
+
+func TestLoadApiRoutes_CatchAll(t *testing.T) {
+	t.Cleanup(func() { _ = os.RemoveAll("api") })
+
+	_ = os.MkdirAll("api/files/_...path", 0755)
+	_ = os.WriteFile("api/files/_...path/index.go", []byte("// test"), 0644)
+
+	r := &Router{}
+	r.loadApiRoutes()
+
+	if len(r.apiRoutes) != 1 {
+		t.Fatalf("expected 1 API route, got %d", len(r.apiRoutes))
+	}
+	if !r.apiRoutes[0].URLPattern.MatchString("files/a/b.txt") {
+		t.Errorf("expected catch-all API route to match nested path")
+	}
+}
+
+func TestCheckImportable(t *testing.T) {
+	if err := CheckImportable("routes/blog/__page"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := CheckImportable("routes/blog/_[...slug]")
+	if err == nil || !strings.Contains(err.Error(), `"__...slug"`) {
+		t.Errorf("expected rename hint, got %v", err)
+	}
+}
+
+func TestLoadRoutes_KeepsServerFilesUnderBracketSegments(t *testing.T) {
+	t.Cleanup(func() { _ = os.RemoveAll("api") })
+	t.Cleanup(cleanupTestArtifacts)
+
+	_ = os.MkdirAll("api/list/_[page]", 0755)
+	_ = os.WriteFile("api/list/_[page]/index.go", []byte("// test"), 0644)
+	_ = os.MkdirAll("routes/blog/_[page]", 0755)
+	_ = os.WriteFile("routes/blog/_[page]/index.html", []byte("blog"), 0644)
+	_ = os.WriteFile("routes/blog/_[page]/index.server.go", []byte("// test"), 0644)
+
+	r := &Router{quiet: true}
+	r.loadRoutes()
+	r.loadApiRoutes()
+
+	if len(r.apiRoutes) != 1 || len(r.routes) != 1 || len(r.skipped) != 0 {
+		t.Errorf("expected bracket routes to load, got %+v, %+v, skipped %+v", r.routes, r.apiRoutes, r.skipped)
+	}
+}
+
+func TestRouter_DoubleUnderscoreDirIsOptional(t *testing.T) {
+	t.Cleanup(cleanupTestArtifacts)
+
+	_ = os.MkdirAll("routes/list/__page", 0755)
+	_ = os.WriteFile("routes/list/__page/index.html", []byte(`<!-- layout: layout.html -->
+{{ define "content" }}page={{ .page }}{{ end }}`), 0644)
+	_ = os.WriteFile("routes/list/__page/index.server.go", []byte(""), 0644)
+	_ = os.WriteFile("layout.html", []byte(`{{ define "layout" }}{{ template "content" . }}{{ end }}`), 0644)
+	_ = os.MkdirAll("components", 0755)
+
+	original := ExecuteServerFile
+	ExecuteServerFile = func(_ string, _ *http.Request, params map[string]string) (map[string]interface{}, error) {
+		return map[string]interface{}{"page": params["page"]}, nil
+	}
+	defer func() { ExecuteServerFile = original }()
+
+	router := NewRouter(Config{OutputDir: t.TempDir()}, RuntimeContext{Env: "dev"})
+
+	for path, expected := range map[string]string{"/list": "page=", "/list/2": "page=2"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != expected {
+			t.Errorf("%s: expected %q, got %d %q", path, expected, rec.Code, rec.Body.String())
+		}
+	}
+}
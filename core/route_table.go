@@ -0,0 +1,202 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type SkippedRoute struct {
+	Path string
+	Err  error
+}
+
+type RouteInfo struct {
+	Kind        string   `json:"kind"`
+	Pattern     string   `json:"pattern"`
+	Params      []string `json:"params"`
+	Dir         string   `json:"dir"`
+	Template    string   `json:"template,omitempty"`
+	Layout      string   `json:"layout,omitempty"`
+	ServerFile  string   `json:"serverFile,omitempty"`
+	Plugin      bool     `json:"plugin"`
+	Methods     []string `json:"methods,omitempty"`
+	Middleware  []string `json:"middleware,omitempty"`
+	ShadowedBy  []string `json:"shadowedBy,omitempty"`
+	Unreachable bool     `json:"unreachable"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+func LoadRouteTable(config Config) []RouteInfo {
+	r := &Router{config: config, env: "dev", quiet: true}
+	r.loadRoutes()
+	r.loadApiRoutes()
+
+	table := []RouteInfo{}
+	rules := compileRules(config.Redirects, nil, nil)
+
+	for _, route := range r.routes {
+		info := RouteInfo{
+			Kind:       "page",
+			Dir:        route.FilePath,
+			Params:     nonNilStrings(route.ParamKeys),
+			Template:   route.HTMLPath,
+			Middleware: route.Middleware,
+		}
+		if layout := r.getLayoutPath(route.HTMLPath); layout != "" {
+			info.Layout = layout
+		}
+		if fileExists(route.ServerPath) {
+			info.ServerFile = route.ServerPath
+			info.Plugin = fileExists(strings.TrimSuffix(route.ServerPath, ".go") + ".so")
+		}
+
+		segments, _ := parseRouteSegments(strings.TrimPrefix(route.FilePath, "routes"))
+		info.Pattern = displayPattern("", segments)
+		annotateConflicts(&info, segments, r.conflicts)
+
+		if len(segments) > 0 && segments[0].Kind == segmentStatic && segments[0].Value == "api" {
+			info.Unreachable = true
+			info.Warnings = append(info.Warnings, "pages under routes/api are never matched; /api/ requests go to api/")
+		}
+		if isStaticPattern(segments) {
+			for _, rule := range rules.redirects {
+				if rule.URLPattern.MatchString(strings.Trim(info.Pattern, "/")) {
+					info.Unreachable = true
+					info.Warnings = append(info.Warnings, fmt.Sprintf("always redirected by rule %s → %s", rule.Source, rule.Destination))
+					break
+				}
+			}
+		}
+
+		table = append(table, info)
+	}
+
+	for _, route := range r.apiRoutes {
+		info := RouteInfo{
+			Kind:       "api",
+			Dir:        route.FilePath,
+			Params:     nonNilStrings(route.ParamKeys),
+			ServerFile: route.ServerPath,
+			Plugin:     fileExists(strings.TrimSuffix(route.ServerPath, ".go") + ".so"),
+			Methods:    route.Methods,
+			Middleware: route.Middleware,
+		}
+
+		segments, _ := parseRouteSegments(strings.TrimPrefix(route.FilePath, "api"))
+		info.Pattern = displayPattern("/api", segments)
+		annotateConflicts(&info, segments, r.apiConflicts)
+
+		table = append(table, info)
+	}
+
+	for _, skipped := range r.skipped {
+		kind := "page"
+		if strings.HasPrefix(skipped.Path, "api") {
+			kind = "api"
+		}
+		table = append(table, RouteInfo{
+			Kind:        kind,
+			Pattern:     "/" + strings.TrimPrefix(filepath.ToSlash(skipped.Path), "routes/"),
+			Params:      []string{},
+			Dir:         skipped.Path,
+			Unreachable: true,
+			Warnings:    []string{skipped.Err.Error()},
+		})
+	}
+
+	sort.SliceStable(table, func(i, j int) bool {
+		if table[i].Kind != table[j].Kind {
+			return table[i].Kind == "page"
+		}
+		return precedenceLess(table[i].Dir, table[j].Dir)
+	})
+
+	return table
+}
+
+func annotateConflicts(info *RouteInfo, segments []routeSegment, conflicts []RouteConflict) {
+	shadowed := 0
+	for _, conflict := range conflicts {
+		if conflict.Shadowed == info.Dir {
+			info.ShadowedBy = append(info.ShadowedBy, conflict.Winner)
+			shadowed++
+		}
+	}
+	if shadowed == 0 {
+		return
+	}
+
+	if shadowed >= len(expandOptionalSegments(segments)) {
+		info.Unreachable = true
+		info.Warnings = append(info.Warnings, "fully shadowed by "+strings.Join(info.ShadowedBy, ", "))
+	} else {
+		info.Warnings = append(info.Warnings, "partially shadowed by "+strings.Join(info.ShadowedBy, ", "))
+	}
+}
+
+func displayPattern(prefix string, segments []routeSegment) string {
+	parts := []string{}
+	for _, seg := range segments {
+		switch seg.Kind {
+		case segmentStatic:
+			parts = append(parts, seg.Value)
+		case segmentParam:
+			parts = append(parts, ":"+seg.Value+seg.Ext)
+		case segmentOptional:
+			parts = append(parts, ":"+seg.Value+seg.Ext+"?")
+		case segmentCatchAll:
+			parts = append(parts, "*"+seg.Value)
+		case segmentOptionalCatchAll:
+			parts = append(parts, "*"+seg.Value+"?")
+		}
+	}
+	if len(parts) == 0 && prefix != "" {
+		return prefix
+	}
+	return prefix + "/" + strings.Join(parts, "/")
+}
+
+func isStaticPattern(segments []routeSegment) bool {
+	for _, seg := range segments {
+		if seg.Kind != segmentStatic {
+			return false
+		}
+	}
+	return true
+}
+
+func precedenceLess(a, b string) bool {
+	pa := strings.Split(filepath.ToSlash(a), "/")[1:]
+	pb := strings.Split(filepath.ToSlash(b), "/")[1:]
+
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		sa, sb := parseRouteSegment(pa[i]), parseRouteSegment(pb[i])
+		if ra, rb := segmentRank(sa), segmentRank(sb); ra != rb {
+			return ra < rb
+		}
+		if pa[i] != pb[i] {
+			return pa[i] < pb[i]
+		}
+	}
+	return len(pa) < len(pb)
+}
+
+func segmentRank(seg routeSegment) int {
+	switch seg.Kind {
+	case segmentStatic:
+		return 0
+	case segmentParam, segmentOptional:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
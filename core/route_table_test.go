@@ -0,0 +1,70 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadRouteTable_OrderAndFlags(t *testing.T) {
+	tmp := t.TempDir()
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	_ = os.Chdir(tmp)
+
+	write := func(path, content string) {
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		_ = os.WriteFile(path, []byte(content), 0644)
+	}
+
+	write("routes/index.html", "<!-- layout: components/layouts/layout.html -->\nhome")
+	write("components/layouts/layout.html", `{{ define "layout" }}{{ end }}`)
+	write("routes/blog/_slug/index.html", "post")
+	write("routes/blog/_slug/index.server.go", "package slug")
+	write("routes/blog/_slug/index.server.so", "")
+	write("routes/blog/_id/index.html", "shadowed")
+	write("routes/blog/latest/index.html", "latest")
+	write("routes/docs/_...path/index.html", "docs")
+	write("routes/old/index.html", "old")
+	write("routes/api/oops/index.html", "never")
+	write("routes/bad/_...rest/more/index.html", "invalid")
+	write("api/users/_id/get.go", "package users\n\nfunc HandleGet() {}\n")
+
+	table := LoadRouteTable(Config{Redirects: []RedirectRule{{Source: "/old", Destination: "/new"}}})
+
+	patterns := []string{}
+	byPattern := map[string]RouteInfo{}
+	for _, info := range table {
+		patterns = append(patterns, info.Pattern)
+		byPattern[info.Pattern] = info
+	}
+
+	expected := []string{"/", "/api/oops", "/bad/_...rest/more", "/blog/latest", "/blog/:id", "/blog/:slug", "/docs/*path", "/old", "/api/users/:id"}
+	if !reflect.DeepEqual(patterns, expected) {
+		t.Fatalf("expected order %v, got %v", expected, patterns)
+	}
+
+	if home := byPattern["/"]; home.Layout != "components/layouts/layout.html" || home.Template != filepath.Join("routes", "index.html") {
+		t.Errorf("unexpected home route info: %+v", home)
+	}
+
+	slug := byPattern["/blog/:slug"]
+	if !slug.Unreachable || len(slug.ShadowedBy) != 1 || slug.ShadowedBy[0] != filepath.Join("routes", "blog", "_id") {
+		t.Errorf("expected /blog/:slug to be shadowed by _id, got %+v", slug)
+	}
+	if !slug.Plugin || slug.ServerFile == "" || !reflect.DeepEqual(slug.Params, []string{"slug"}) {
+		t.Errorf("expected server file with plugin, got %+v", slug)
+	}
+
+	for _, pattern := range []string{"/old", "/api/oops", "/bad/_...rest/more"} {
+		if !byPattern[pattern].Unreachable || len(byPattern[pattern].Warnings) == 0 {
+			t.Errorf("expected %s to be flagged unreachable, got %+v", pattern, byPattern[pattern])
+		}
+	}
+
+	users := byPattern["/api/users/:id"]
+	if users.Kind != "api" || !reflect.DeepEqual(users.Methods, []string{"GET"}) || users.Plugin {
+		t.Errorf("unexpected API route info: %+v", users)
+	}
+}
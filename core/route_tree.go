@@ -0,0 +1,182 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+type routeNode struct {
+	static   map[string]*routeNode
+	param    *routeNode
+	catchAll *routeNode
+	leaf     *routeLeaf
+}
+
+type routeLeaf struct {
+	index int
+	slots []int
+}
+
+type routeTree struct {
+	root       *routeNode
+	paramCount []int
+}
+
+type RouteConflict struct {
+	Pattern  string
+	Winner   string
+	Shadowed string
+}
+
+func (c RouteConflict) String() string {
+	return fmt.Sprintf("%s shadows %s (both match /%s)", c.Winner, c.Shadowed, c.Pattern)
+}
+
+func buildRouteTree(filePaths []string, root string) (*routeTree, []RouteConflict) {
+	tree := &routeTree{
+		root:       &routeNode{},
+		paramCount: make([]int, len(filePaths)),
+	}
+	conflicts := []RouteConflict{}
+
+	for index, filePath := range filePaths {
+		segments, err := parseRouteSegments(strings.TrimPrefix(filePath, root))
+		if err != nil {
+			continue
+		}
+
+		for _, seg := range segments {
+			if seg.Kind != segmentStatic {
+				tree.paramCount[index]++
+			}
+		}
+
+		for _, variant := range expandOptionalSegments(segments) {
+			node, slots, shape := tree.root, []int{}, []string{}
+			slot := 0
+			for _, seg := range variant.segments {
+				switch seg.Kind {
+				case segmentStatic:
+					if node.static == nil {
+						node.static = map[string]*routeNode{}
+					}
+					if node.static[seg.Value] == nil {
+						node.static[seg.Value] = &routeNode{}
+					}
+					node = node.static[seg.Value]
+					shape = append(shape, seg.Value)
+				case segmentCatchAll, segmentOptionalCatchAll:
+					if node.catchAll == nil {
+						node.catchAll = &routeNode{}
+					}
+					node = node.catchAll
+					shape = append(shape, "*")
+				default:
+					if node.param == nil {
+						node.param = &routeNode{}
+					}
+					node = node.param
+					shape = append(shape, ":")
+				}
+				if seg.Kind != segmentStatic {
+					slots = append(slots, variant.slots[slot])
+					slot++
+				}
+			}
+
+			if node.leaf != nil {
+				if node.leaf.index != index {
+					conflicts = append(conflicts, RouteConflict{
+						Pattern:  strings.Join(shape, "/"),
+						Winner:   filePaths[node.leaf.index],
+						Shadowed: filePath,
+					})
+				}
+				continue
+			}
+			node.leaf = &routeLeaf{index: index, slots: slots}
+		}
+	}
+
+	return tree, conflicts
+}
+
+type segmentVariant struct {
+	segments []routeSegment
+	slots    []int
+}
+
+func expandOptionalSegments(segments []routeSegment) []segmentVariant {
+	variants := []segmentVariant{{}}
+	slot := 0
+
+	for _, seg := range segments {
+		next := []segmentVariant{}
+		for _, v := range variants {
+			withSeg := segmentVariant{
+				segments: append(append([]routeSegment{}, v.segments...), seg),
+				slots:    v.slots,
+			}
+			if seg.Kind != segmentStatic {
+				withSeg.slots = append(append([]int{}, v.slots...), slot)
+			}
+			next = append(next, withSeg)
+			if seg.isOptional() {
+				next = append(next, v)
+			}
+		}
+		if seg.Kind != segmentStatic {
+			slot++
+		}
+		variants = next
+	}
+
+	return variants
+}
+
+func (t *routeTree) match(path string) (int, []string) {
+	if t == nil {
+		return -1, nil
+	}
+
+	parts := []string{}
+	if path != "" {
+		parts = strings.Split(path, "/")
+	}
+
+	leaf, captured := t.root.match(parts, nil)
+	if leaf == nil {
+		return -1, nil
+	}
+
+	matches := make([]string, t.paramCount[leaf.index]+1)
+	matches[0] = path
+	for i, value := range captured {
+		matches[leaf.slots[i]+1] = value
+	}
+	return leaf.index, matches
+}
+
+func (n *routeNode) match(parts []string, captured []string) (*routeLeaf, []string) {
+	if len(parts) == 0 {
+		return n.leaf, captured
+	}
+
+	if child := n.static[parts[0]]; child != nil {
+		if leaf, values := child.match(parts[1:], captured); leaf != nil {
+			return leaf, values
+		}
+	}
+
+	if n.param != nil && parts[0] != "" {
+		if leaf, values := n.param.match(parts[1:], append(captured, parts[0])); leaf != nil {
+			return leaf, values
+		}
+	}
+
+	if n.catchAll != nil && n.catchAll.leaf != nil {
+		return n.catchAll.leaf, append(captured, strings.Join(parts, "/"))
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestRouteTree_StaticBeatsParamBeatsCatchAll(t *testing.T) {
+	tree, _ := buildRouteTree([]string{
+		"routes/docs/_...slug",
+		"routes/docs/_id",
+		"routes/docs/intro",
+	}, "routes")
+
+	cases := map[string]int{
+		"docs/intro":   2,
+		"docs/other":   1,
+		"docs/a/b/c":   0,
+		"docs/intro/x": 0,
+	}
+
+	for path, expected := range cases {
+		if index, _ := tree.match(path); index != expected {
+			t.Errorf("%s: expected route %d, got %d", path, expected, index)
+		}
+	}
+}
+
+func TestRouteTree_DeeperStaticPrefixWins(t *testing.T) {
+	tree, conflicts := buildRouteTree([]string{
+		"routes/_section/latest",
+		"routes/blog/_slug",
+	}, "routes")
+
+	if len(conflicts) != 0 {
+		t.Fatalf("expected no conflicts, got %v", conflicts)
+	}
+
+	index, matches := tree.match("blog/latest")
+	if index != 1 {
+		t.Fatalf("expected blog/_slug to win, got route %d", index)
+	}
+	if matches[1] != "latest" {
+		t.Errorf("expected captured slug 'latest', got %v", matches)
+	}
+
+	if index, _ := tree.match("news/latest"); index != 0 {
+		t.Errorf("expected _section/latest for news/latest, got %d", index)
+	}
+}
+
+func TestRouteTree_BacktracksWhenStaticBranchFails(t *testing.T) {
+	tree, _ := buildRouteTree([]string{
+		"routes/blog/archive",
+		"routes/_section/_page",
+	}, "routes")
+
+	index, matches := tree.match("blog/2")
+	if index != 1 {
+		t.Fatalf("expected fallback to _section/_page, got %d", index)
+	}
+	if matches[1] != "blog" || matches[2] != "2" {
+		t.Errorf("unexpected captures: %v", matches)
+	}
+}
+
+func TestRouteTree_OptionalSegments(t *testing.T) {
+	tree, _ := buildRouteTree([]string{
+		"routes/_[lang]/about",
+	}, "routes")
+
+	if index, matches := tree.match("about"); index != 0 || matches[1] != "" {
+		t.Errorf("expected match without lang, got %d %v", index, matches)
+	}
+	if index, matches := tree.match("en/about"); index != 0 || matches[1] != "en" {
+		t.Errorf("expected match with lang, got %d %v", index, matches)
+	}
+}
+
+func TestRouteTree_ReportsConflicts(t *testing.T) {
+	_, conflicts := buildRouteTree([]string{
+		"routes/blog/_slug",
+		"routes/blog/_id",
+		"routes/blog/_[page]",
+	}, "routes")
+
+	if len(conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %v", conflicts)
+	}
+	if conflicts[0].Winner != "routes/blog/_slug" || conflicts[0].Shadowed != "routes/blog/_id" {
+		t.Errorf("unexpected conflict: %v", conflicts[0])
+	}
+	if conflicts[1].Pattern != "blog/:" {
+		t.Errorf("expected pattern blog/:, got %q", conflicts[1].Pattern)
+	}
+}
+
+func TestRouteTree_RootAndMissing(t *testing.T) {
+	tree, _ := buildRouteTree([]string{"routes", "routes/about"}, "routes")
+
+	if index, _ := tree.match(""); index != 0 {
+		t.Errorf("expected root route, got %d", index)
+	}
+	if index, _ := tree.match("missing"); index != -1 {
+		t.Errorf("expected no match, got %d", index)
+	}
+
+	var empty *routeTree
+	if index, _ := empty.match("about"); index != -1 {
+		t.Errorf("expected nil tree to match nothing, got %d", index)
+	}
+}
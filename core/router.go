@@ -3,9 +3,11 @@ package core
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"fmt"
-	"html/template"
+	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -26,14 +28,32 @@ type Route struct {
 	HTMLPath     string
 	ServerPath   string
 	FilePath     string
+	Middleware   []string
 }
 
 type Router struct {
 	config         Config
 	env            string
 	onReload       func()
+	services       map[string]interface{}
 	routes         []Route
 	apiRoutes      []ApiRoute
+	routeTree      *routeTree
+	apiTree        *routeTree
+	conflicts      []RouteConflict
+	apiConflicts   []RouteConflict
+	rules          *ruleSet
+	timeouts       *timeoutSet
+	limits         []concurrencyLimit
+	skipped        []SkippedRoute
+	quiet          bool
+	fsys           fs.FS
+	configFile     string
+	workers        *workerSet
+	logger         io.Writer
+	done           chan struct{}
+	closeOnce      sync.Once
+	routesMu       sync.RWMutex
 	componentFiles []string
 	templateCache  sync.Map
 	layoutCache    sync.Map
@@ -43,6 +63,10 @@ type RuntimeContext struct {
 	Env         string
 	EnableWatch bool
 	OnReload    func()
+	Services    map[string]interface{}
+	FS          fs.FS
+	ConfigFile  string
+	Logger      io.Writer
 }
 
 type statusRecorder struct {
@@ -76,6 +100,18 @@ type cacheWriteRequest struct {
 	Ext      string
 }
 
+const reloadScript = `
+<script>
+	if (typeof WebSocket !== "undefined") {
+		const protocol = location.protocol === "https:" ? "wss" : "ws";
+		const ws = new WebSocket(protocol + "://" + location.host + "%s/__barry_reload");
+		ws.onmessage = e => {
+			if (e.data === "reload") location.reload();
+		};
+	}
+</script>
+</body>`
+
 var cacheLocks sync.Map
 var compileLocks sync.Map
 var cacheQueue = make(chan cacheWriteRequest, 100)
@@ -96,10 +132,23 @@ func init() {
 }
 
 var NewRouter = func(config Config, ctx RuntimeContext) http.Handler {
+	config.BasePath = NormalizeBasePath(config.BasePath)
 	r := &Router{
-		config:   config,
-		env:      ctx.Env,
-		onReload: ctx.OnReload,
+		config:     config,
+		env:        ctx.Env,
+		onReload:   ctx.OnReload,
+		services:   ctx.Services,
+		fsys:       ctx.FS,
+		configFile: ctx.ConfigFile,
+		workers:    newWorkerSet(ctx.Logger),
+		logger:     ctx.Logger,
+		done:       make(chan struct{}),
+	}
+	r.setRules(config.Redirects, config.Rewrites)
+	r.setTimeouts(config.Timeout, config.Timeouts)
+	r.setConcurrency(config.Concurrency)
+	if !validTrailingSlash(config.TrailingSlash) {
+		r.logf("⚠️ Unknown trailingSlash %q, expected always, never or ignore\n", config.TrailingSlash)
 	}
 
 	var wg sync.WaitGroup
@@ -127,8 +176,9 @@ var NewRouter = func(config Config, ctx RuntimeContext) http.Handler {
 
 func (r *Router) loadRoutes() {
 	routes := []Route{}
+	r.clearSkipped("routes")
 
-	_ = filepath.WalkDir("routes", func(path string, d os.DirEntry, err error) error {
+	_ = siteWalkDir(r.fsys, "routes", func(path string, d os.DirEntry, err error) error {
 		if err != nil || !d.IsDir() {
 			return nil
 		}
@@ -140,71 +190,181 @@ func (r *Router) loadRoutes() {
 		htmlPath := filepath.Join(path, "index.html")
 		xmlPath := filepath.Join(path, "index.xml")
 
-		hasHTML := fileExists(htmlPath)
-		hasXML := fileExists(xmlPath)
+		hasHTML := r.fileExists(htmlPath)
+		hasXML := r.fileExists(xmlPath)
 
 		if !hasHTML && !hasXML {
 			return nil
 		}
 
 		rel := strings.TrimPrefix(path, "routes")
-		parts := strings.Split(strings.Trim(rel, "/"), "/")
-		paramKeys := []string{}
-		paramRawKeys := []string{}
-		pattern := ""
-
-		for _, part := range parts {
-			if strings.HasPrefix(part, "_") {
-				rawKey := part[1:]
-				cleanKey := strings.TrimSuffix(rawKey, filepath.Ext(rawKey))
-
-				paramRawKeys = append(paramRawKeys, rawKey)
-				paramKeys = append(paramKeys, cleanKey)
-				pattern += "/([^/]+)"
-			} else {
-				pattern += "/" + part
-			}
+		segments, err := parseRouteSegments(rel)
+		if err != nil {
+			r.skipRoute(path, err)
+			return nil
 		}
 
-		regex := regexp.MustCompile("^" + strings.TrimPrefix(pattern, "/") + "$")
+		regex, paramKeys, paramRawKeys := compileRoutePattern(segments)
 
 		routes = append(routes, Route{
 			URLPattern:   regex,
 			ParamKeys:    paramKeys,
 			ParamRawKeys: paramRawKeys,
-			HTMLPath:     choose(htmlPath, xmlPath),
+			HTMLPath:     r.choose(htmlPath, xmlPath),
 			ServerPath:   filepath.Join(path, "index.server.go"),
 			FilePath:     path,
+			Middleware:   findMiddleware(r.fsys, "routes", path),
 		})
 
 		return nil
 	})
 
 	sort.SliceStable(routes, func(i, j int) bool {
-		isDynamic := func(parts []string) bool {
+		rank := func(parts []string) int {
+			result := 0
 			for _, part := range parts {
-				if strings.HasPrefix(part, "_") {
-					return true
+				seg := parseRouteSegment(part)
+				if seg.isCatchAll() {
+					return 2
+				}
+				if seg.Kind != segmentStatic {
+					result = 1
 				}
 			}
-			return false
+			return result
 		}
 		pi := strings.Split(strings.TrimPrefix(routes[i].FilePath, "routes/"), "/")
 		pj := strings.Split(strings.TrimPrefix(routes[j].FilePath, "routes/"), "/")
 
-		return !isDynamic(pi) && isDynamic(pj)
+		return rank(pi) < rank(pj)
 	})
 
+	r.setRoutes(routes)
+}
+
+func (r *Router) setRoutes(routes []Route) {
+	filePaths := make([]string, len(routes))
+	for i, route := range routes {
+		filePaths[i] = route.FilePath
+	}
+
+	tree, conflicts := buildRouteTree(filePaths, "routes")
+	for _, conflict := range conflicts {
+		r.warnf("⚠️ Route conflict: %s\n", conflict)
+	}
+
+	r.routesMu.Lock()
 	r.routes = routes
+	r.routeTree = tree
+	r.conflicts = conflicts
+	r.routesMu.Unlock()
+}
+
+func (r *Router) warnf(format string, args ...interface{}) {
+	if !r.quiet {
+		r.logf(format, args...)
+	}
+}
+
+func (r *Router) logf(format string, args ...interface{}) {
+	logf(r.logger, format, args...)
 }
 
-func choose(a, b string) string {
-	if fileExists(a) {
+type loggerKey struct{}
+
+func withLogger(ctx context.Context, out io.Writer) context.Context {
+	return context.WithValue(ctx, loggerKey{}, out)
+}
+
+func requestLogger(req *http.Request) io.Writer {
+	if req == nil {
+		return nil
+	}
+	out, _ := req.Context().Value(loggerKey{}).(io.Writer)
+	return out
+}
+
+func logf(out io.Writer, format string, args ...interface{}) {
+	if out == nil {
+		out = os.Stdout
+	}
+	fmt.Fprintf(out, format, args...)
+}
+
+func (r *Router) Reload() {
+	r.loadRoutes()
+	r.loadApiRoutes()
+	r.loadComponentFiles()
+	r.templateCache.Clear()
+	r.layoutCache.Clear()
+}
+
+func (r *Router) Close() error {
+	r.closeOnce.Do(func() {
+		if r.done != nil {
+			close(r.done)
+		}
+		if r.workers != nil {
+			r.workers.stop()
+		}
+	})
+	return nil
+}
+
+func (r *Router) skipRoute(path string, err error) {
+	r.routesMu.Lock()
+	r.skipped = append(r.skipped, SkippedRoute{Path: path, Err: err})
+	r.routesMu.Unlock()
+	r.warnf("⚠️ Skipping route %s: %v\n", path, err)
+}
+
+func (r *Router) clearSkipped(root string) {
+	r.routesMu.Lock()
+	kept := r.skipped[:0]
+	for _, skipped := range r.skipped {
+		if !strings.HasPrefix(skipped.Path, root) {
+			kept = append(kept, skipped)
+		}
+	}
+	r.skipped = kept
+	r.routesMu.Unlock()
+}
+
+func (r *Router) matchRoute(path string) (Route, map[string]string, bool) {
+	r.routesMu.RLock()
+	defer r.routesMu.RUnlock()
+
+	index, matches := r.routeTree.match(path)
+	if index < 0 {
+		return Route{}, nil, false
+	}
+	route := r.routes[index]
+	return route, extractParams(route.ParamKeys, route.ParamRawKeys, matches), true
+}
+
+func (r *Router) matchApiRoute(path string) (ApiRoute, map[string]string, bool) {
+	r.routesMu.RLock()
+	defer r.routesMu.RUnlock()
+
+	index, matches := r.apiTree.match(path)
+	if index < 0 {
+		return ApiRoute{}, nil, false
+	}
+	route := r.apiRoutes[index]
+	return route, extractParams(route.ParamKeys, route.ParamRawKeys, matches), true
+}
+
+func (r *Router) choose(a, b string) string {
+	if r.fileExists(a) {
 		return a
 	}
 	return b
 }
 
+func (r *Router) fileExists(path string) bool {
+	return SiteFileExists(r.fsys, path)
+}
+
 func fileExists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
@@ -212,18 +372,26 @@ func fileExists(path string) bool {
 
 func (r *Router) loadComponentFiles() {
 	var files []string
-	_ = filepath.Walk("components", func(path string, info os.FileInfo, err error) error {
-		if err == nil && !info.IsDir() && strings.HasSuffix(path, ".html") {
+	_ = siteWalkDir(r.fsys, "components", func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.HasSuffix(path, ".html") {
 			files = append(files, path)
 		}
 		return nil
 	})
+	r.routesMu.Lock()
 	r.componentFiles = files
+	r.routesMu.Unlock()
+}
+
+func (r *Router) components() []string {
+	r.routesMu.RLock()
+	defer r.routesMu.RUnlock()
+	return r.componentFiles
 }
 
 func (r *Router) serveStatic(htmlPath, serverPath string, w http.ResponseWriter, req *http.Request, params map[string]string, resolvedPath string) {
-	if _, err := os.Stat(htmlPath); err != nil {
-		r.renderErrorPage(w, http.StatusNotFound, "Page not found", req.URL.Path)
+	if _, err := siteStat(r.fsys, htmlPath); err != nil {
+		r.renderErrorPage(w, req, http.StatusNotFound, "Page not found")
 		return
 	}
 
@@ -240,7 +408,7 @@ func (r *Router) serveStatic(htmlPath, serverPath string, w http.ResponseWriter,
 				etag := generateETag(data)
 				if match := req.Header.Get("If-None-Match"); match == etag {
 					if r.config.DebugLogs {
-						fmt.Printf("🧩 304 Not Modified (gzip): /%s\n", routeKey)
+						r.logf("🧩 304 Not Modified (gzip): /%s\n", routeKey)
 					}
 					w.WriteHeader(http.StatusNotModified)
 					return
@@ -254,7 +422,7 @@ func (r *Router) serveStatic(htmlPath, serverPath string, w http.ResponseWriter,
 					w.Header().Set("X-Barry-Cache", "HIT")
 				}
 				if r.config.DebugLogs {
-					fmt.Printf("📦 Cache HIT (gzip): /%s\n", routeKey)
+					r.logf("📦 Cache HIT (gzip): /%s\n", routeKey)
 				}
 				w.Write(data)
 				return
@@ -265,7 +433,7 @@ func (r *Router) serveStatic(htmlPath, serverPath string, w http.ResponseWriter,
 			etag := generateETag(data)
 			if match := req.Header.Get("If-None-Match"); match == etag {
 				if r.config.DebugLogs {
-					fmt.Printf("🧩 304 Not Modified: /%s\n", routeKey)
+					r.logf("🧩 304 Not Modified: /%s\n", routeKey)
 				}
 				w.WriteHeader(http.StatusNotModified)
 				return
@@ -277,53 +445,75 @@ func (r *Router) serveStatic(htmlPath, serverPath string, w http.ResponseWriter,
 				w.Header().Set("X-Barry-Cache", "HIT")
 			}
 			if r.config.DebugLogs {
-				fmt.Printf("📦 Cache HIT: /%s\n", routeKey)
+				r.logf("📦 Cache HIT: /%s\n", routeKey)
 			}
 			w.Write(data)
 			return
 		}
 	}
 
-	data := map[string]interface{}{}
-	if fileExists(serverPath) {
-		lock := getOrCreateCompileLock(serverPath)
-		lock.Lock()
-		result, err := ExecuteServerFile(serverPath, req, params)
-		lock.Unlock()
+	data := map[string]interface{}{
+		"CanonicalURL": r.canonicalURL(req),
+	}
+	for k, v := range middlewareData(req) {
+		data[k] = v
+	}
+	meta := ResponseMeta{}
+	if r.fileExists(serverPath) {
+		execReq, slot := withResponseMeta(req)
+		result, err := ExecuteServerFile(serverPath, execReq, params)
 		if err != nil {
-			if IsNotFoundError(err) {
-				r.renderErrorPage(w, http.StatusNotFound, "Page not found", req.URL.Path)
-				return
-			}
-			http.Error(w, "Server logic error: "+err.Error(), http.StatusInternalServerError)
+			r.handleExecError(w, req, err, false, "Server logic error: ")
+			return
+		}
+		meta = slot.get()
+		meta.apply(w)
+		if meta.Redirect != "" {
+			http.Redirect(w, req, meta.Redirect, meta.redirectStatus())
 			return
 		}
-		data = result
+		for k, v := range result {
+			data[k] = v
+		}
 	}
 
 	layoutPath := r.getLayoutPath(htmlPath)
+	if layoutPath != "" && !isXML {
+		layout, err := r.layoutData(req, layoutPath, params)
+		if err != nil {
+			r.handleExecError(w, req, err, false, "Layout logic error: ")
+			return
+		}
+		r.mergeLayoutData(data, layout)
+	}
+
 	tmplFiles := []string{}
-	if layoutPath != "" && fileExists(layoutPath) {
+	if layoutPath != "" && r.fileExists(layoutPath) {
 		tmplFiles = append(tmplFiles, layoutPath)
 	}
 	tmplFiles = append(tmplFiles, htmlPath)
-	tmplFiles = append(tmplFiles, r.componentFiles...)
+	tmplFiles = append(tmplFiles, r.components()...)
 
-	cacheKey := hashTemplateFiles(tmplFiles)
+	cacheKey := hashTemplateFiles(r.fsys, tmplFiles)
 
-	var tmpl *template.Template
+	var page *pageTemplate
 	if val, ok := r.templateCache.Load(cacheKey); ok {
-		tmpl = val.(*template.Template)
+		page = val.(*pageTemplate)
 	} else {
-		tmpl = template.New("").Funcs(BarryTemplateFuncs(r.env, r.config.OutputDir))
-		parsed, err := tmpl.ParseFiles(tmplFiles...)
+		parsed, err := r.parsePageTemplate(tmplFiles)
 		if err != nil {
-			fmt.Printf("❌ Template parse error [%s]: %v\n", cacheKey, err)
+			r.logf("❌ Template parse error [%s]: %v\n", cacheKey, err)
 			http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 		actual, _ := r.templateCache.LoadOrStore(cacheKey, parsed)
-		tmpl = actual.(*template.Template)
+		page = actual.(*pageTemplate)
+	}
+
+	tmpl, err := page.forRequest(r, req)
+	if err != nil {
+		http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	var rendered bytes.Buffer
@@ -340,17 +530,7 @@ func (r *Router) serveStatic(htmlPath, serverPath string, w http.ResponseWriter,
 	html := rendered.Bytes()
 
 	if r.env == "dev" && !isXML {
-		html = bytes.Replace(html, []byte("</body>"), []byte(`
-<script>
-	if (typeof WebSocket !== "undefined") {
-		const protocol = location.protocol === "https:" ? "wss" : "ws";
-		const ws = new WebSocket(protocol + "://" + location.host + "/__barry_reload");
-		ws.onmessage = e => {
-			if (e.data === "reload") location.reload();
-		};
-	}
-</script>
-</body>`), 1)
+		html = bytes.Replace(html, []byte("</body>"), []byte(fmt.Sprintf(reloadScript, r.config.BasePath)), 1)
 	}
 
 	w.Header().Set("Content-Type", getContentType(htmlPath))
@@ -358,12 +538,15 @@ func (r *Router) serveStatic(htmlPath, serverPath string, w http.ResponseWriter,
 	if r.config.DebugHeaders {
 		w.Header().Set("X-Barry-Cache", "MISS")
 	}
+	if meta.Status != 0 {
+		w.WriteHeader(meta.Status)
+	}
 	w.Write(html)
 	if f, ok := w.(http.Flusher); ok {
 		f.Flush()
 	}
 
-	if r.config.CacheEnabled {
+	if r.config.CacheEnabled && meta.empty() {
 		lock := getOrCreateLock(routeKey)
 		ext := getFileExt(htmlPath)
 		req := cacheWriteRequest{
@@ -377,20 +560,20 @@ func (r *Router) serveStatic(htmlPath, serverPath string, w http.ResponseWriter,
 		select {
 		case cacheQueue <- req:
 			if r.config.DebugLogs {
-				fmt.Printf("📝 Enqueued cache write: /%s\n", routeKey)
+				r.logf("📝 Enqueued cache write: /%s\n", routeKey)
 			}
 		default:
 			if r.config.DebugLogs {
-				fmt.Printf("⚠️  Cache queue full — writing immediately for: /%s\n", routeKey)
+				r.logf("⚠️  Cache queue full — writing immediately for: /%s\n", routeKey)
 			}
 			go func() {
 				req.Lock.Lock()
 				err := SaveCachedHTMLFunc(req.Config, req.RouteKey, req.Ext, req.HTML)
 				req.Lock.Unlock()
 				if err != nil {
-					fmt.Printf("❌ Cache write failed (immediate): /%s → %v\n", req.RouteKey, err)
+					r.logf("❌ Cache write failed (immediate): /%s → %v\n", req.RouteKey, err)
 				} else {
-					fmt.Printf("✅ Cache write complete (immediate): /%s\n", req.RouteKey)
+					r.logf("✅ Cache write complete (immediate): /%s\n", req.RouteKey)
 				}
 			}()
 		}
@@ -419,62 +602,70 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	path := strings.Trim(req.URL.Path, "/")
 	recorder := &statusRecorder{ResponseWriter: w, status: 200}
 
-	if strings.HasPrefix(path, "api/") {
-		apiPath := strings.TrimPrefix(path, "api/")
-		for _, route := range r.apiRoutes {
-			if matches := route.URLPattern.FindStringSubmatch(apiPath); matches != nil {
-				params := map[string]string{}
-				for i, key := range route.ParamKeys {
-					rawKey := route.ParamRawKeys[i]
-					ext := filepath.Ext(rawKey)
-					value := matches[i+1]
-
-					if ext != "" && strings.HasSuffix(value, ext) {
-						value = strings.TrimSuffix(value, ext)
-					}
+	if !strings.HasPrefix(path, "api/") {
+		if canonical, ok := r.canonicalRedirect(req); ok {
+			http.Redirect(recorder, req, withRedirectQuery(canonical, req), http.StatusPermanentRedirect)
+			return
+		}
+	}
 
-					params[key] = value
-				}
+	if target, status, ok := r.matchRedirect(path); ok {
+		http.Redirect(recorder, req, withRedirectQuery(target, req), status)
+		if r.env == "dev" && shouldLogRequest(req.URL.Path) {
+			r.logf("%s %d → %s\n", req.URL.Path, status, target)
+		}
+		return
+	}
+
+	if target, ok := r.matchRewrite(path); ok {
+		req, path = rewriteRequest(req, target)
+	}
 
+	ctx := withURLBuilder(req.Context(), r.fsys, r.config)
+	if r.workers != nil {
+		ctx = withWorkers(ctx, r.workers)
+	}
+	if r.logger != nil {
+		ctx = withLogger(ctx, r.logger)
+	}
+	if len(r.services) > 0 {
+		ctx = WithServices(ctx, r.services)
+	}
+	req = req.WithContext(ctx)
+
+	req, cancel := r.withExecTimeout(req, path)
+	defer cancel()
+
+	release, err := r.acquireRoute(req, path)
+	if err != nil {
+		r.handleExecError(recorder, req, err, strings.HasPrefix(path, "api/"), "Server error: ")
+		return
+	}
+	defer release()
+
+	if strings.HasPrefix(path, "api/") {
+		apiPath := strings.TrimPrefix(path, "api/")
+		if route, params, ok := r.matchApiRoute(apiPath); ok {
+			if req, params, ok := r.applyMiddleware(recorder, req, route.Middleware, params, true); ok {
 				r.handleAPI(recorder, req, route, params)
-				return
 			}
+			return
 		}
 		http.Error(w, "API route not found", http.StatusNotFound)
 		return
 	}
 
-	if path == "" {
-		r.serveStatic("routes/index.html", "routes/index.server.go", recorder, req, map[string]string{}, "")
-	} else {
-		found := false
-		for _, route := range r.routes {
-			if matches := route.URLPattern.FindStringSubmatch(path); matches != nil {
-				params := map[string]string{}
-				for i, key := range route.ParamKeys {
-					rawKey := route.ParamRawKeys[i]
-					ext := filepath.Ext(rawKey)
-					value := matches[i+1]
-
-					if ext != "" && strings.HasSuffix(value, ext) {
-						value = strings.TrimSuffix(value, ext)
-					}
-
-					params[key] = value
-				}
-				r.serveStatic(route.HTMLPath, route.ServerPath, recorder, req, params, path)
-				found = true
-				break
-			}
-		}
-		if !found {
-			r.renderErrorPage(recorder, http.StatusNotFound, "Page not found", req.URL.Path)
+	if route, params, ok := r.matchRoute(path); ok {
+		if req, params, ok := r.applyMiddleware(recorder, req, route.Middleware, params, false); ok {
+			r.serveStatic(route.HTMLPath, route.ServerPath, recorder, req, params, path)
 		}
+	} else {
+		r.renderErrorPage(recorder, req, http.StatusNotFound, "Page not found")
 	}
 
 	if r.env == "dev" && shouldLogRequest(req.URL.Path) {
 		duration := time.Since(start).Milliseconds()
-		fmt.Printf("%s %d %dms\n", req.URL.Path, recorder.Status(), duration)
+		r.logf("%s %d %dms\n", req.URL.Path, recorder.Status(), duration)
 	}
 }
 
@@ -485,7 +676,7 @@ func (r *Router) watchEverything() {
 	}
 	defer watcher.Close()
 
-	watchDirs := []string{"routes", "components", "public"}
+	watchDirs := []string{"routes", "api", "components", "public"}
 
 	addDirs := func() {
 		for _, base := range watchDirs {
@@ -496,6 +687,9 @@ func (r *Router) watchEverything() {
 				return nil
 			})
 		}
+		if r.configFile != "" && fileExists(r.configFile) {
+			_ = watcher.Add(r.configFile)
+		}
 	}
 
 	addDirs()
@@ -504,28 +698,41 @@ func (r *Router) watchEverything() {
 	if !debounce.Stop() {
 		<-debounce.C
 	}
+	changed := map[string]bool{}
 
 	for {
 		select {
+		case <-r.done:
+			return
 		case event, ok := <-watcher.Events:
 			if !ok {
 				return
 			}
+			if filepath.Ext(event.Name) == ".so" {
+				continue
+			}
 			if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) != 0 {
+				changed[filepath.Clean(event.Name)] = true
 				debounce.Reset(100 * time.Millisecond)
 			}
 		case <-debounce.C:
-			r.loadRoutes()
+			paths := make([]string, 0, len(changed))
+			for path := range changed {
+				paths = append(paths, path)
+			}
+			changed = map[string]bool{}
+
+			r.reloadChanged(paths)
 			addDirs()
 			if r.env == "dev" && r.onReload != nil {
-				fmt.Println("🔄 Change detected and reloaded")
+				r.logf("🔄 Change detected and reloaded\n")
 				r.onReload()
 			}
 		case err, ok := <-watcher.Errors:
 			if !ok {
 				return
 			}
-			fmt.Println("❌ Watch error:", err)
+			r.logf("❌ Watch error: %v\n", err)
 		}
 	}
 }
@@ -565,11 +772,11 @@ func acceptsGzip(r *http.Request) bool {
 	return strings.Contains(r.Header.Get("Accept-Encoding"), "gzip")
 }
 
-func hashTemplateFiles(paths []string) string {
+func hashTemplateFiles(fsys fs.FS, paths []string) string {
 	h := sha256.New()
 	for _, p := range paths {
 		h.Write([]byte(p))
-		if info, err := os.Stat(p); err == nil {
+		if info, err := siteStat(fsys, p); err == nil {
 			mtime := info.ModTime().UnixNano()
 			fmt.Fprintf(h, "%d", mtime)
 		}
@@ -582,7 +789,7 @@ func (r *Router) getLayoutPath(file string) string {
 		return val.(string)
 	}
 
-	f, err := os.Open(file)
+	f, err := siteOpen(r.fsys, file)
 	if err != nil {
 		r.layoutCache.Store(file, "")
 		return ""
@@ -600,14 +807,14 @@ func (r *Router) getLayoutPath(file string) string {
 	}
 
 	if err := scanner.Err(); err != nil {
-		fmt.Printf("❌ Error scanning %s for layout directive: %v\n", file, err)
+		r.warnf("❌ Error scanning %s for layout directive: %v\n", file, err)
 	}
 
 	actual, _ := r.layoutCache.LoadOrStore(file, "")
 	return actual.(string)
 }
 
-func (r *Router) renderErrorPage(w http.ResponseWriter, status int, message, path string) {
+func (r *Router) renderErrorPage(w http.ResponseWriter, req *http.Request, status int, message string) {
 	base := "routes/_error"
 	statusFile := fmt.Sprintf("%s/%d.html", base, status)
 	defaultFile := fmt.Sprintf("%s/index.html", base)
@@ -616,7 +823,7 @@ func (r *Router) renderErrorPage(w http.ResponseWriter, status int, message, pat
 		"Title":       fmt.Sprintf("%d - %s", status, message),
 		"StatusCode":  status,
 		"Message":     message,
-		"Path":        path,
+		"Path":        req.URL.Path,
 		"Description": message,
 	}
 
@@ -626,22 +833,22 @@ func (r *Router) renderErrorPage(w http.ResponseWriter, status int, message, pat
 		tmplFiles := []string{}
 
 		if layoutPath != "" {
-			if _, err := os.Stat(layoutPath); err == nil {
+			if _, err := siteStat(r.fsys, layoutPath); err == nil {
 				tmplFiles = append(tmplFiles, layoutPath)
 			} else {
-				fmt.Printf("⚠️ Skipping missing layout: %q\n", layoutPath)
+				r.logf("⚠️ Skipping missing layout: %q\n", layoutPath)
 			}
 		}
 
 		if file != "" {
-			if _, err := os.Stat(file); err == nil {
+			if _, err := siteStat(r.fsys, file); err == nil {
 				tmplFiles = append(tmplFiles, file)
 			} else {
-				fmt.Printf("⚠️ Skipping missing error template: %s\n", file)
+				r.logf("⚠️ Skipping missing error template: %s\n", file)
 			}
 		}
 
-		for _, f := range r.componentFiles {
+		for _, f := range r.components() {
 			if f != "" {
 				tmplFiles = append(tmplFiles, f)
 			}
@@ -652,12 +859,28 @@ func (r *Router) renderErrorPage(w http.ResponseWriter, status int, message, pat
 			return false
 		}
 
+		if layoutPath != "" {
+			layoutReq, cancel := r.detachedRequest(req)
+			layout, err := r.layoutData(layoutReq, layoutPath, nil)
+			cancel()
+			if err != nil {
+				r.logf("⚠️ Skipping layout data for error page: %v\n", err)
+			}
+			r.mergeLayoutData(context, layout)
+		}
+
 		name := filepath.Base(file)
 
-		tmpl := template.New("").Funcs(BarryTemplateFuncs(r.env, r.config.OutputDir))
-		tmpl, err := tmpl.ParseFiles(tmplFiles...)
+		page, err := r.parsePageTemplate(tmplFiles)
+		if err != nil {
+			r.logf("❌ Error parsing error page: %v\n", err)
+			http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
+			return false
+		}
+
+		tmpl, err := page.forRequest(r, req)
 		if err != nil {
-			fmt.Println("❌ Error parsing error page:", err)
+			r.logf("❌ Error parsing error page: %v\n", err)
 			http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
 			return false
 		}
@@ -671,7 +894,7 @@ func (r *Router) renderErrorPage(w http.ResponseWriter, status int, message, pat
 		}
 
 		if err != nil {
-			fmt.Println("❌ Error executing error template:", err)
+			r.logf("❌ Error executing error template: %v\n", err)
 			http.Error(w, "Template execution error: "+err.Error(), http.StatusInternalServerError)
 			return false
 		}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -10,76 +11,168 @@ import (
 
 type ApiRoute struct {
 	Method       string
+	Methods      []string
 	URLPattern   *regexp.Regexp
 	ParamKeys    []string
 	ParamRawKeys []string
 	ServerPath   string
 	FilePath     string
+	Middleware   []string
 }
 
 func (r *Router) loadApiRoutes() {
 	routes := []ApiRoute{}
+	r.clearSkipped("api")
 
-	_ = filepath.WalkDir("api", func(path string, d os.DirEntry, err error) error {
+	_ = siteWalkDir(r.fsys, "api", func(path string, d os.DirEntry, err error) error {
 		if err != nil || !d.IsDir() {
 			return nil
 		}
 
-		filePath := filepath.Join(path, "index.go")
-		if _, err := os.Stat(filePath); err != nil {
-			filePath = filepath.Join(path, "index.server.go")
-			if _, err := os.Stat(filePath); err != nil {
-				return nil
-			}
+		filePath := findApiFile(r.fsys, path)
+		if filePath == "" {
+			return nil
 		}
 
 		rel := strings.TrimPrefix(path, "api")
-		parts := strings.Split(strings.Trim(rel, "/"), "/")
-		paramKeys := []string{}
-		paramRawKeys := []string{}
-		pattern := ""
-
-		for _, part := range parts {
-			if strings.HasPrefix(part, "_") {
-				rawKey := part[1:]
-				cleanKey := strings.TrimSuffix(rawKey, filepath.Ext(rawKey))
-
-				paramRawKeys = append(paramRawKeys, rawKey)
-				paramKeys = append(paramKeys, cleanKey)
-				pattern += "/([^/]+)"
-			} else {
-				pattern += "/" + part
-			}
+		segments, err := parseRouteSegments(rel)
+		if err != nil {
+			r.skipRoute(path, err)
+			return nil
 		}
 
-		regex := regexp.MustCompile("^" + strings.TrimPrefix(pattern, "/") + "$")
+		regex, paramKeys, paramRawKeys := compileRoutePattern(segments)
+
+		method := "ANY"
+		handlers, err := detectHandlers(r.fsys, path)
+		if err != nil {
+			r.warnf("⚠️ Could not inspect handlers in %s: %v\n", path, err)
+		}
+		methods := handlerMethods(handlers)
+		if len(methods) > 0 && !containsString(handlers, defaultHandlerName) {
+			method = strings.Join(methods, ",")
+		}
 
 		routes = append(routes, ApiRoute{
-			Method:       "ANY",
+			Method:       method,
+			Methods:      methods,
 			URLPattern:   regex,
 			ParamKeys:    paramKeys,
 			ParamRawKeys: paramRawKeys,
 			ServerPath:   filePath,
 			FilePath:     path,
+			Middleware:   findMiddleware(r.fsys, "api", path),
 		})
 
 		return nil
 	})
 
+	r.setApiRoutes(routes)
+}
+
+func FindApiFile(dir string) string {
+	return findApiFile(nil, dir)
+}
+
+func findApiFile(fsys fs.FS, dir string) string {
+	candidates := []string{"index.go", "index.server.go"}
+	for _, mh := range methodHandlers {
+		candidates = append(candidates, strings.ToLower(mh.Method)+".go")
+	}
+
+	for _, name := range candidates {
+		filePath := filepath.Join(dir, name)
+		if _, err := siteStat(fsys, filePath); err == nil {
+			return filePath
+		}
+	}
+	return ""
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Router) setApiRoutes(routes []ApiRoute) {
+	filePaths := make([]string, len(routes))
+	for i, route := range routes {
+		filePaths[i] = route.FilePath
+	}
+
+	tree, conflicts := buildRouteTree(filePaths, "api")
+	for _, conflict := range conflicts {
+		r.warnf("⚠️ API route conflict: %s\n", conflict)
+	}
+
+	r.routesMu.Lock()
 	r.apiRoutes = routes
+	r.apiTree = tree
+	r.apiConflicts = conflicts
+	r.routesMu.Unlock()
+}
+
+func (route ApiRoute) handlerFor(method string) string {
+	if containsString(route.Methods, method) {
+		return methodHandlerName(method)
+	}
+	if method == http.MethodHead && containsString(route.Methods, http.MethodGet) {
+		return methodHandlerName(http.MethodGet)
+	}
+	if route.Method == "ANY" || route.Method == "" || len(route.Methods) == 0 {
+		return defaultHandlerName
+	}
+	return ""
+}
+
+func (route ApiRoute) allowHeader() string {
+	allowed := append([]string{}, route.Methods...)
+	if containsString(allowed, http.MethodGet) && !containsString(allowed, http.MethodHead) {
+		allowed = append(allowed, http.MethodHead)
+	}
+	if !containsString(allowed, http.MethodOptions) {
+		allowed = append(allowed, http.MethodOptions)
+	}
+	return strings.Join(allowed, ", ")
 }
 
 func (r *Router) handleAPI(w http.ResponseWriter, req *http.Request, route ApiRoute, params map[string]string) {
-	result, err := ExecuteAPIFile(route.ServerPath, req, params)
-	if err != nil {
-		if IsNotFoundError(err) {
-			http.Error(w, "Not Found", http.StatusNotFound)
+	handler := route.handlerFor(req.Method)
+	if handler == "" {
+		w.Header().Set("Allow", route.allowHeader())
+		if req.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		http.Error(w, "Server error: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if handler != defaultHandlerName {
+		req = withHandlerName(req, handler)
+	}
+
+	execReq, slot := withResponseMeta(req)
+	result, err := ExecuteAPIFile(route.ServerPath, execReq, params)
+	if err != nil {
+		r.handleExecError(w, req, err, true, "Server error: ")
+		return
+	}
+
+	meta := slot.get()
+	meta.apply(w)
+	if meta.Redirect != "" {
+		http.Redirect(w, req, meta.Redirect, meta.redirectStatus())
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if meta.Status != 0 {
+		w.WriteHeader(meta.Status)
+	}
 	w.Write(result)
 }
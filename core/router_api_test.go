@@ -159,3 +159,113 @@ func TestHandleAPI_InternalError(t *testing.T) {
 		t.Errorf("expected 'Server error' in response")
 	}
 }
+
+func TestLoadApiRoutes_PerMethodFiles(t *testing.T) {
+	tmp := t.TempDir()
+
+	apiPath := filepath.Join(tmp, "api", "users")
+	_ = os.MkdirAll(apiPath, 0755)
+	_ = os.WriteFile(filepath.Join(apiPath, "get.go"), []byte("package users\n\nfunc HandleGet() {}\n"), 0644)
+	_ = os.WriteFile(filepath.Join(apiPath, "post.go"), []byte("package users\n\nfunc HandlePost() {}\n"), 0644)
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	_ = os.Chdir(tmp)
+
+	r := &Router{}
+	r.loadApiRoutes()
+
+	if len(r.apiRoutes) != 1 {
+		t.Fatalf("expected 1 API route, got %d", len(r.apiRoutes))
+	}
+
+	route := r.apiRoutes[0]
+	if route.ServerPath != filepath.Join("api", "users", "get.go") {
+		t.Errorf("expected get.go as server path, got %s", route.ServerPath)
+	}
+	if route.Method != "GET,POST" {
+		t.Errorf("expected method GET,POST, got %s", route.Method)
+	}
+}
+
+func TestHandleAPI_DispatchesByMethod(t *testing.T) {
+	defer func() { ExecuteAPIFile = _origExecuteAPIFile }()
+
+	var handler string
+	ExecuteAPIFile = func(path string, req *http.Request, params map[string]string) ([]byte, error) {
+		handler = HandlerName(req)
+		return []byte(`{}`), nil
+	}
+
+	r := &Router{env: "dev"}
+	route := ApiRoute{Method: "GET,POST", Methods: []string{"GET", "POST"}, ServerPath: "fake/path"}
+
+	for method, expected := range map[string]string{
+		http.MethodGet:  "HandleGet",
+		http.MethodHead: "HandleGet",
+		http.MethodPost: "HandlePost",
+	} {
+		handler = ""
+		rec := httptest.NewRecorder()
+		r.handleAPI(rec, httptest.NewRequest(method, "/api/users", nil), route, map[string]string{})
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", method, rec.Code)
+		}
+		if handler != expected {
+			t.Errorf("%s: expected %s, got %s", method, expected, handler)
+		}
+	}
+}
+
+func TestHandleAPI_MethodNotAllowed(t *testing.T) {
+	defer func() { ExecuteAPIFile = _origExecuteAPIFile }()
+
+	ExecuteAPIFile = func(path string, req *http.Request, params map[string]string) ([]byte, error) {
+		t.Error("handler should not be called")
+		return nil, nil
+	}
+
+	r := &Router{env: "dev"}
+	route := ApiRoute{Method: "GET,POST", Methods: []string{"GET", "POST"}, ServerPath: "fake/path"}
+
+	rec := httptest.NewRecorder()
+	r.handleAPI(rec, httptest.NewRequest(http.MethodDelete, "/api/users", nil), route, map[string]string{})
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+	if allow := rec.Header().Get("Allow"); allow != "GET, POST, HEAD, OPTIONS" {
+		t.Errorf("unexpected Allow header: %q", allow)
+	}
+
+	rec = httptest.NewRecorder()
+	r.handleAPI(rec, httptest.NewRequest(http.MethodOptions, "/api/users", nil), route, map[string]string{})
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected 204 for OPTIONS, got %d", rec.Code)
+	}
+	if rec.Header().Get("Allow") == "" {
+		t.Error("expected Allow header on OPTIONS response")
+	}
+}
+
+func TestHandleAPI_FallsBackToHandleRequest(t *testing.T) {
+	defer func() { ExecuteAPIFile = _origExecuteAPIFile }()
+
+	var handler string
+	ExecuteAPIFile = func(path string, req *http.Request, params map[string]string) ([]byte, error) {
+		handler = HandlerName(req)
+		return []byte(`{}`), nil
+	}
+
+	r := &Router{env: "dev"}
+	route := ApiRoute{Method: "ANY", Methods: []string{"GET"}, ServerPath: "fake/path"}
+
+	rec := httptest.NewRecorder()
+	r.handleAPI(rec, httptest.NewRequest(http.MethodPatch, "/api/users", nil), route, map[string]string{})
+
+	if handler != "HandleRequest" {
+		t.Errorf("expected HandleRequest fallback, got %s", handler)
+	}
+}
@@ -98,14 +98,14 @@ func TestRouter_ServesMatchingRoute(t *testing.T) {
 
 	router := NewRouter(cfg, RuntimeContext{Env: "dev"}).(*Router)
 
-	router.routes = []Route{
+	router.setRoutes([]Route{
 		{
 			URLPattern: regexp.MustCompile("^test$"),
 			HTMLPath:   "routes/test/index.html",
 			ServerPath: "routes/test/index.server.go",
 			FilePath:   "routes/test",
 		},
-	}
+	})
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 	rec := httptest.NewRecorder()
@@ -195,14 +195,14 @@ func TestRouter_EnqueuesCacheWrite(t *testing.T) {
 
 	router := NewRouter(cfg, RuntimeContext{Env: "dev"}).(*Router)
 
-	router.routes = []Route{
+	router.setRoutes([]Route{
 		{
 			URLPattern: regexp.MustCompile("^test$"),
 			HTMLPath:   "routes/test/index.html",
 			ServerPath: "routes/test/index.server.go",
 			FilePath:   "routes/test",
 		},
-	}
+	})
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 	rec := httptest.NewRecorder()
@@ -235,14 +235,14 @@ func TestRouter_ServesFromGzipCache(t *testing.T) {
 	_ = os.WriteFile(filepath.Join(cacheDir, "index.html.gz"), buf.Bytes(), 0644)
 
 	router := NewRouter(cfg, RuntimeContext{Env: "prod"}).(*Router)
-	router.routes = []Route{
+	router.setRoutes([]Route{
 		{
 			URLPattern: regexp.MustCompile("^test$"),
 			HTMLPath:   "routes/test/index.html",
 			ServerPath: "routes/test/index.server.go",
 			FilePath:   "routes/test",
 		},
-	}
+	})
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 	req.Header.Set("Accept-Encoding", "gzip")
@@ -273,7 +273,7 @@ func TestRenderErrorPage_Fallback(t *testing.T) {
 	router := NewRouter(cfg, RuntimeContext{Env: "dev"}).(*Router)
 
 	rec := httptest.NewRecorder()
-	router.renderErrorPage(rec, http.StatusInternalServerError, "Something broke", "/fail")
+	router.renderErrorPage(rec, httptest.NewRequest(http.MethodGet, "/fail", nil), http.StatusInternalServerError, "Something broke")
 
 	if rec.Result().StatusCode != http.StatusInternalServerError {
 		t.Errorf("expected 500 status code, got %d", rec.Result().StatusCode)
@@ -316,12 +316,12 @@ func TestRouter_ServerFileReturnsNotFoundError(t *testing.T) {
 
 	router := NewRouter(cfg, RuntimeContext{Env: "dev"}).(*Router)
 
-	router.routes = []Route{{
+	router.setRoutes([]Route{{
 		URLPattern: regexp.MustCompile("^fail$"),
 		HTMLPath:   "routes/fail/index.html",
 		ServerPath: "routes/fail/index.server.go",
 		FilePath:   "routes/fail",
-	}}
+	}})
 
 	original := ExecuteServerFile
 	ExecuteServerFile = func(_ string, _ *http.Request, _ map[string]string) (map[string]interface{}, error) {
@@ -375,12 +375,12 @@ func TestRouter_ServerFileReturnsGenericError(t *testing.T) {
 
 	router := NewRouter(cfg, RuntimeContext{Env: "dev"}).(*Router)
 
-	router.routes = []Route{{
+	router.setRoutes([]Route{{
 		URLPattern: regexp.MustCompile("^fail$"),
 		HTMLPath:   "routes/fail/index.html",
 		ServerPath: "routes/fail/index.server.go",
 		FilePath:   "routes/fail",
-	}}
+	}})
 
 	original := ExecuteServerFile
 	ExecuteServerFile = func(_ string, _ *http.Request, _ map[string]string) (map[string]interface{}, error) {
@@ -519,12 +519,12 @@ func TestRouter_ServeStatic_Gzip304Logging(t *testing.T) {
 	_ = os.WriteFile(filepath.Join(cfg.OutputDir, "test", "index.html.gz"), buf.Bytes(), 0644)
 
 	router := NewRouter(cfg, RuntimeContext{Env: "prod"}).(*Router)
-	router.routes = []Route{{
+	router.setRoutes([]Route{{
 		URLPattern: regexp.MustCompile("^test$"),
 		HTMLPath:   "routes/test/index.html",
 		ServerPath: "routes/test/index.server.go",
 		FilePath:   "routes/test",
-	}}
+	}})
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 	req.Header.Set("Accept-Encoding", "gzip")
@@ -562,12 +562,12 @@ func TestRouter_ServesFromHTMLCache_304(t *testing.T) {
 	_ = os.MkdirAll("components", 0755)
 
 	router := NewRouter(cfg, RuntimeContext{Env: "prod"}).(*Router)
-	router.routes = []Route{{
+	router.setRoutes([]Route{{
 		URLPattern: regexp.MustCompile("^test$"),
 		HTMLPath:   "routes/test/index.html",
 		ServerPath: "routes/test/index.server.go",
 		FilePath:   "routes/test",
-	}}
+	}})
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 	req.Header.Set("If-None-Match", etag)
@@ -602,12 +602,12 @@ func TestRouter_ServesFromHTMLCache_WithHeaders(t *testing.T) {
 	_ = os.MkdirAll("components", 0755)
 
 	router := NewRouter(cfg, RuntimeContext{Env: "prod"}).(*Router)
-	router.routes = []Route{{
+	router.setRoutes([]Route{{
 		URLPattern: regexp.MustCompile("^test$"),
 		HTMLPath:   "routes/test/index.html",
 		ServerPath: "routes/test/index.server.go",
 		FilePath:   "routes/test",
-	}}
+	}})
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 	rec := httptest.NewRecorder()
@@ -660,12 +660,12 @@ func TestRouter_CacheQueueFull_ImmediateWriteWithLogs(t *testing.T) {
 	}
 
 	router := NewRouter(cfg, RuntimeContext{Env: "dev"}).(*Router)
-	router.routes = []Route{{
+	router.setRoutes([]Route{{
 		URLPattern: regexp.MustCompile("^test$"),
 		HTMLPath:   "routes/test/index.html",
 		ServerPath: "routes/test/index.server.go",
 		FilePath:   "routes/test",
-	}}
+	}})
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 	rec := httptest.NewRecorder()
@@ -696,12 +696,12 @@ func TestRouter_TemplateParseError(t *testing.T) {
 	_ = os.MkdirAll("components", 0755)
 
 	router := NewRouter(cfg, RuntimeContext{Env: "dev"}).(*Router)
-	router.routes = []Route{{
+	router.setRoutes([]Route{{
 		URLPattern: regexp.MustCompile("^test$"),
 		HTMLPath:   "routes/test/index.html",
 		ServerPath: "routes/test/index.server.go",
 		FilePath:   "routes/test",
-	}}
+	}})
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 	rec := httptest.NewRecorder()
@@ -731,12 +731,12 @@ func TestRouter_ServerFile_GenericErrorNoTemplate(t *testing.T) {
 	_ = os.MkdirAll("components", 0755)
 
 	router := NewRouter(cfg, RuntimeContext{Env: "dev"}).(*Router)
-	router.routes = []Route{{
+	router.setRoutes([]Route{{
 		URLPattern: regexp.MustCompile("^fail$"),
 		HTMLPath:   "routes/fail/index.html",
 		ServerPath: "routes/fail/index.server.go",
 		FilePath:   "routes/fail",
-	}}
+	}})
 
 	original := ExecuteServerFile
 	ExecuteServerFile = func(_ string, _ *http.Request, _ map[string]string) (map[string]interface{}, error) {
@@ -773,12 +773,12 @@ func TestRouter_ServeStatic_MissingLayout_LogsWarning(t *testing.T) {
 	_ = os.MkdirAll("components", 0755)
 
 	router := NewRouter(cfg, RuntimeContext{Env: "dev"}).(*Router)
-	router.routes = []Route{{
+	router.setRoutes([]Route{{
 		URLPattern: regexp.MustCompile("^test$"),
 		HTMLPath:   "routes/test/index.html",
 		ServerPath: "routes/test/index.server.go",
 		FilePath:   "routes/test",
-	}}
+	}})
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 	rec := httptest.NewRecorder()
@@ -811,12 +811,12 @@ func TestRouter_ServeStatic_SetsMissHeader(t *testing.T) {
 	_ = os.MkdirAll("components", 0755)
 
 	router := NewRouter(cfg, RuntimeContext{Env: "dev"}).(*Router)
-	router.routes = []Route{{
+	router.setRoutes([]Route{{
 		URLPattern: regexp.MustCompile("^test$"),
 		HTMLPath:   "routes/test/index.html",
 		ServerPath: "routes/test/index.server.go",
 		FilePath:   "routes/test",
-	}}
+	}})
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 	rec := httptest.NewRecorder()
@@ -849,12 +849,12 @@ func TestRouter_UsesCachedTemplate(t *testing.T) {
 	_ = os.MkdirAll("components", 0755)
 
 	router := NewRouter(cfg, RuntimeContext{Env: "dev"}).(*Router)
-	router.routes = []Route{{
+	router.setRoutes([]Route{{
 		URLPattern: regexp.MustCompile("^test$"),
 		HTMLPath:   "routes/test/index.html",
 		ServerPath: "routes/test/index.server.go",
 		FilePath:   "routes/test",
-	}}
+	}})
 
 	req1 := httptest.NewRequest(http.MethodGet, "/test", nil)
 	rec1 := httptest.NewRecorder()
@@ -904,12 +904,12 @@ func TestRouter_CacheQueueFull_ImmediateWriteErrorLogs(t *testing.T) {
 	}
 
 	router := NewRouter(cfg, RuntimeContext{Env: "dev"}).(*Router)
-	router.routes = []Route{{
+	router.setRoutes([]Route{{
 		URLPattern: regexp.MustCompile("^test$"),
 		HTMLPath:   "routes/test/index.html",
 		ServerPath: "routes/test/index.server.go",
 		FilePath:   "routes/test",
-	}}
+	}})
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 	rec := httptest.NewRecorder()
@@ -971,14 +971,14 @@ func TestRouter_ParsesAndInjectsParams_WithExtension(t *testing.T) {
 	defer func() { ExecuteServerFile = original }()
 
 	router := NewRouter(cfg, RuntimeContext{Env: "dev"}).(*Router)
-	router.routes = []Route{{
+	router.setRoutes([]Route{{
 		URLPattern:   regexp.MustCompile("^posts/([^/]+)$"),
 		ParamKeys:    []string{"id"},
 		ParamRawKeys: []string{"_id.html"},
 		HTMLPath:     "routes/posts/_id/index.html",
 		ServerPath:   "routes/posts/_id/index.server.go",
 		FilePath:     "routes/posts/_id",
-	}}
+	}})
 
 	req := httptest.NewRequest(http.MethodGet, "/posts/123.html", nil)
 	rec := httptest.NewRecorder()
@@ -1005,17 +1005,16 @@ func TestServeHTTP_API_ParamSuffixStripped(t *testing.T) {
 		return []byte(`{"ok":true}`), nil
 	}
 
-	r := &Router{
-		env: "dev",
-		apiRoutes: []ApiRoute{
-			{
-				URLPattern:   regexp.MustCompile("^item/([^/]+)$"),
-				ParamKeys:    []string{"slug"},
-				ParamRawKeys: []string{"_slug.json"},
-				ServerPath:   "api/item/_slug/index.go",
-			},
+	r := &Router{env: "dev"}
+	r.setApiRoutes([]ApiRoute{
+		{
+			URLPattern:   regexp.MustCompile("^item/([^/]+)$"),
+			ParamKeys:    []string{"slug"},
+			ParamRawKeys: []string{"_slug.json"},
+			ServerPath:   "api/item/_slug/index.go",
+			FilePath:     "api/item/_slug",
 		},
-	}
+	})
 
 	req := httptest.NewRequest("GET", "/api/item/example.json", nil)
 	rec := httptest.NewRecorder()
@@ -1085,12 +1084,12 @@ func TestRouter_ServeStatic_MissingLayoutWithDebugLogs(t *testing.T) {
 	_ = os.MkdirAll("components", 0755)
 
 	router := NewRouter(cfg, RuntimeContext{Env: "dev"}).(*Router)
-	router.routes = []Route{{
+	router.setRoutes([]Route{{
 		URLPattern: regexp.MustCompile("^test$"),
 		HTMLPath:   "routes/test/index.html",
 		ServerPath: "routes/test/index.server.go",
 		FilePath:   "routes/test",
-	}}
+	}})
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 	rec := httptest.NewRecorder()
@@ -1128,7 +1127,7 @@ func TestRouter_RenderErrorPage_MissingLayoutFallback(t *testing.T) {
 	router := NewRouter(cfg, RuntimeContext{Env: "dev"}).(*Router)
 
 	rec := httptest.NewRecorder()
-	router.renderErrorPage(rec, http.StatusNotFound, "Missing layout", "/bad")
+	router.renderErrorPage(rec, httptest.NewRequest(http.MethodGet, "/bad", nil), http.StatusNotFound, "Missing layout")
 
 	if rec.Code != http.StatusNotFound {
 		t.Errorf("expected 404 fallback, got %d", rec.Code)
@@ -1158,7 +1157,7 @@ func TestRouter_RenderErrorPage_TemplateParseError(t *testing.T) {
 	router := NewRouter(cfg, RuntimeContext{Env: "dev"}).(*Router)
 
 	rec := httptest.NewRecorder()
-	router.renderErrorPage(rec, http.StatusNotFound, "Invalid template syntax", "/broken")
+	router.renderErrorPage(rec, httptest.NewRequest(http.MethodGet, "/broken", nil), http.StatusNotFound, "Invalid template syntax")
 
 	if rec.Code != http.StatusInternalServerError {
 		t.Errorf("expected 500 due to parse error, got %d", rec.Code)
@@ -1178,18 +1177,16 @@ func TestServeHTTP_API_MatchesAndInvokesHandler(t *testing.T) {
 		return []byte(`{"ok":true}`), nil
 	}
 
-	r := &Router{
-		env: "dev",
-		apiRoutes: []ApiRoute{
-			{
-				URLPattern:   regexp.MustCompile("^hello/([^/]+)$"),
-				ParamKeys:    []string{"id"},
-				ParamRawKeys: []string{"_id"},
-				ServerPath:   "api/hello/_id/index.go",
-				FilePath:     "api/hello/_id",
-			},
+	r := &Router{env: "dev"}
+	r.setApiRoutes([]ApiRoute{
+		{
+			URLPattern:   regexp.MustCompile("^hello/([^/]+)$"),
+			ParamKeys:    []string{"id"},
+			ParamRawKeys: []string{"_id"},
+			ServerPath:   "api/hello/_id/index.go",
+			FilePath:     "api/hello/_id",
 		},
-	}
+	})
 
 	req := httptest.NewRequest("GET", "/api/hello/123", nil)
 	rec := httptest.NewRecorder()
@@ -1218,17 +1215,16 @@ func TestServeHTTP_API_MatchesWithMultipleParams(t *testing.T) {
 		return []byte(`{"ok":true}`), nil
 	}
 
-	r := &Router{
-		env: "dev",
-		apiRoutes: []ApiRoute{
-			{
-				URLPattern:   regexp.MustCompile("^user/([^/]+)/profile/([^/]+)$"),
-				ParamKeys:    []string{"userId", "section"},
-				ParamRawKeys: []string{"_userId", "_section"},
-				ServerPath:   "api/user/_userId/profile/_section/index.go",
-			},
+	r := &Router{env: "dev"}
+	r.setApiRoutes([]ApiRoute{
+		{
+			URLPattern:   regexp.MustCompile("^user/([^/]+)/profile/([^/]+)$"),
+			ParamKeys:    []string{"userId", "section"},
+			ParamRawKeys: []string{"_userId", "_section"},
+			ServerPath:   "api/user/_userId/profile/_section/index.go",
+			FilePath:     "api/user/_userId/profile/_section",
 		},
-	}
+	})
 
 	req := httptest.NewRequest("GET", "/api/user/abc/profile/details", nil)
 	rec := httptest.NewRecorder()
@@ -1240,10 +1236,8 @@ func TestServeHTTP_API_MatchesWithMultipleParams(t *testing.T) {
 }
 
 func TestServeHTTP_API_NoMatch_Returns404(t *testing.T) {
-	r := &Router{
-		env:       "dev",
-		apiRoutes: []ApiRoute{},
-	}
+	r := &Router{env: "dev"}
+	r.setApiRoutes([]ApiRoute{})
 
 	req := httptest.NewRequest("GET", "/api/does-not-exist", nil)
 	rec := httptest.NewRecorder()
@@ -1269,17 +1263,16 @@ func TestServeHTTP_API_MatchWithoutParams(t *testing.T) {
 		return []byte(`{"status":"ok"}`), nil
 	}
 
-	r := &Router{
-		env: "dev",
-		apiRoutes: []ApiRoute{
-			{
-				URLPattern:   regexp.MustCompile("^ping$"),
-				ParamKeys:    []string{},
-				ParamRawKeys: []string{},
-				ServerPath:   "api/ping/index.go",
-			},
+	r := &Router{env: "dev"}
+	r.setApiRoutes([]ApiRoute{
+		{
+			URLPattern:   regexp.MustCompile("^ping$"),
+			ParamKeys:    []string{},
+			ParamRawKeys: []string{},
+			ServerPath:   "api/ping/index.go",
+			FilePath:     "api/ping",
 		},
-	}
+	})
 
 	req := httptest.NewRequest("GET", "/api/ping", nil)
 	rec := httptest.NewRecorder()
@@ -1316,12 +1309,12 @@ func TestRouter_ServeStatic_FlushCalled(t *testing.T) {
 	_ = os.MkdirAll("components", 0755)
 
 	router := NewRouter(cfg, RuntimeContext{Env: "dev"}).(*Router)
-	router.routes = []Route{{
+	router.setRoutes([]Route{{
 		URLPattern: regexp.MustCompile("^test$"),
 		HTMLPath:   "routes/test/index.html",
 		ServerPath: "routes/test/index.server.go",
 		FilePath:   "routes/test",
-	}}
+	}})
 
 	rec := httptest.NewRecorder()
 
@@ -1376,7 +1369,7 @@ func TestChoose_ReturnsSecondIfFirstDoesNotExist(t *testing.T) {
 		t.Fatalf("failed to write xml: %v", err)
 	}
 
-	result := choose("nonexistent.html", xmlPath)
+	result := (&Router{}).choose("nonexistent.html", xmlPath)
 
 	if result != xmlPath {
 		t.Errorf("expected xmlPath to be returned, got %s", result)
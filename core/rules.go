@@ -0,0 +1,256 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const configFile = "barry.config.yml"
+
+type compiledRule struct {
+	Source       string
+	Destination  string
+	Status       int
+	URLPattern   *regexp.Regexp
+	ParamKeys    []string
+	ParamRawKeys []string
+	EscapeParams bool
+}
+
+type ruleSet struct {
+	redirects []compiledRule
+	rewrites  []compiledRule
+}
+
+func compileRules(redirects []RedirectRule, rewrites []RewriteRule, out io.Writer) *ruleSet {
+	rules := &ruleSet{}
+
+	for _, redirect := range redirects {
+		rule, err := compileRule(redirect.Source, redirect.Destination)
+		if err != nil {
+			logf(out, "⚠️ Skipping redirect %s: %v\n", redirect.Source, err)
+			continue
+		}
+
+		switch redirect.Status {
+		case 0:
+			rule.Status = http.StatusPermanentRedirect
+		case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+			rule.Status = redirect.Status
+		default:
+			logf(out, "⚠️ Skipping redirect %s: unsupported status %d\n", redirect.Source, redirect.Status)
+			continue
+		}
+
+		rule.EscapeParams = true
+		rules.redirects = append(rules.redirects, rule)
+	}
+
+	for _, rewrite := range rewrites {
+		rule, err := compileRule(rewrite.Source, rewrite.Destination)
+		if err == nil && strings.Contains(rewrite.Destination, "://") {
+			err = fmt.Errorf("destination must be a path within the site")
+		}
+		if err != nil {
+			logf(out, "⚠️ Skipping rewrite %s: %v\n", rewrite.Source, err)
+			continue
+		}
+		rules.rewrites = append(rules.rewrites, rule)
+	}
+
+	return rules
+}
+
+func compileRule(source, destination string) (compiledRule, error) {
+	if destination == "" {
+		return compiledRule{}, fmt.Errorf("missing destination")
+	}
+
+	segments, err := parseRouteSegments(source)
+	if err != nil {
+		return compiledRule{}, err
+	}
+
+	regex, paramKeys, paramRawKeys := compileRoutePattern(segments)
+
+	return compiledRule{
+		Source:       source,
+		Destination:  destination,
+		URLPattern:   regex,
+		ParamKeys:    paramKeys,
+		ParamRawKeys: paramRawKeys,
+	}, nil
+}
+
+func compilePattern(source string) (*regexp.Regexp, error) {
+	segments, err := parseRouteSegments(source)
+	if err != nil {
+		return nil, err
+	}
+
+	regex, _, _ := compileRoutePattern(segments)
+	return regex, nil
+}
+
+func (rule compiledRule) match(path string) (string, bool) {
+	matches := rule.URLPattern.FindStringSubmatch(path)
+	if matches == nil {
+		return "", false
+	}
+	params := extractParams(rule.ParamKeys, rule.ParamRawKeys, matches)
+	return expandDestination(rule.Destination, params, rule.EscapeParams), true
+}
+
+func expandDestination(destination string, params map[string]string, escape bool) string {
+	prefix := ""
+	rest := destination
+
+	if i := strings.Index(rest, "://"); i >= 0 {
+		slash := strings.Index(rest[i+3:], "/")
+		if slash < 0 {
+			return destination
+		}
+		prefix = rest[:i+3+slash]
+		rest = rest[i+3+slash:]
+	}
+
+	suffix := ""
+	if i := strings.IndexAny(rest, "?#"); i >= 0 {
+		suffix = rest[i:]
+		rest = rest[:i]
+	}
+
+	parts := []string{}
+	for i, part := range strings.Split(rest, "/") {
+		seg := parseRouteSegment(part)
+		if seg.Kind == segmentStatic {
+			parts = append(parts, part)
+			continue
+		}
+
+		value, ok := params[seg.Value]
+		if !ok {
+			parts = append(parts, part)
+			continue
+		}
+		if escape {
+			value = escapePathValue(value)
+		}
+		if value == "" && i > 0 {
+			continue
+		}
+		parts = append(parts, value+seg.Ext)
+	}
+
+	return sameSiteTarget(destination, prefix+strings.Join(parts, "/")+suffix)
+}
+
+func escapePathValue(value string) string {
+	parts := []string{}
+	for _, part := range strings.Split(value, "/") {
+		if part != "" {
+			parts = append(parts, url.PathEscape(part))
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func sameSiteTarget(destination, target string) string {
+	if !strings.HasPrefix(destination, "/") || strings.HasPrefix(destination, "//") {
+		return target
+	}
+	if strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return "/" + strings.TrimLeft(target, "/\\")
+	}
+	return target
+}
+
+func (r *Router) setRules(redirects []RedirectRule, rewrites []RewriteRule) {
+	rules := compileRules(redirects, rewrites, r.logger)
+
+	r.routesMu.Lock()
+	r.rules = rules
+	r.routesMu.Unlock()
+}
+
+func (r *Router) loadRules() {
+	if r.configFile == "" {
+		return
+	}
+	cfg := LoadConfigFS(r.fsys, r.configFile)
+	r.setRules(cfg.Redirects, cfg.Rewrites)
+	r.setTimeouts(cfg.Timeout, cfg.Timeouts)
+	r.setConcurrency(cfg.Concurrency)
+}
+
+func (r *Router) matchRedirect(path string) (string, int, bool) {
+	r.routesMu.RLock()
+	defer r.routesMu.RUnlock()
+
+	if r.rules == nil {
+		return "", 0, false
+	}
+	for _, rule := range r.rules.redirects {
+		if target, ok := rule.match(path); ok {
+			return target, rule.Status, true
+		}
+	}
+	return "", 0, false
+}
+
+func (r *Router) matchRewrite(path string) (string, bool) {
+	r.routesMu.RLock()
+	defer r.routesMu.RUnlock()
+
+	if r.rules == nil {
+		return "", false
+	}
+	for _, rule := range r.rules.rewrites {
+		if target, ok := rule.match(path); ok {
+			return target, true
+		}
+	}
+	return "", false
+}
+
+func withRedirectQuery(target string, req *http.Request) string {
+	if req.URL.RawQuery == "" || strings.Contains(target, "?") {
+		return target
+	}
+	if i := strings.Index(target, "#"); i >= 0 {
+		return target[:i] + "?" + req.URL.RawQuery + target[i:]
+	}
+	return target + "?" + req.URL.RawQuery
+}
+
+func rewriteRequest(req *http.Request, target string) (*http.Request, string) {
+	if i := strings.Index(target, "#"); i >= 0 {
+		target = target[:i]
+	}
+
+	path, rawQuery, hasQuery := strings.Cut(target, "?")
+	path = strings.Trim(path, "/")
+	if !hasQuery || rawQuery == "" {
+		return req, path
+	}
+
+	extra, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return req, path
+	}
+
+	query := req.URL.Query()
+	for key, values := range extra {
+		if _, exists := query[key]; !exists {
+			query[key] = values
+		}
+	}
+
+	rewritten := req.Clone(req.Context())
+	rewritten.URL.RawQuery = query.Encode()
+	return rewritten, path
+}
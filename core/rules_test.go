@@ -0,0 +1,190 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExpandDestination_SubstitutesPlaceholders(t *testing.T) {
+	params := map[string]string{"slug": "hello-world", "rest": "a/b", "page": ""}
+
+	cases := map[string]string{
+		"/blog/_slug":                   "/blog/hello-world",
+		"/docs/_...rest":                "/docs/a/b",
+		"/posts/_slug.html?ref=old":     "/posts/hello-world.html?ref=old",
+		"https://example.com/_slug#top": "https://example.com/hello-world#top",
+		"/list/_[page]":                 "/list",
+		"/keep/_unknown":                "/keep/_unknown",
+		"https://example.com":           "https://example.com",
+	}
+
+	for destination, expected := range cases {
+		if got := expandDestination(destination, params, false); got != expected {
+			t.Errorf("%s: expected %s, got %s", destination, expected, got)
+		}
+	}
+}
+
+func TestRouter_RedirectDoesNotLeaveTheSite(t *testing.T) {
+	cfg, cleanup := setupRouterTestEnv(t)
+	defer cleanup()
+
+	cfg.Redirects = []RedirectRule{{Source: "/old/_...rest", Destination: "/_...rest", Status: 301}}
+	router := NewRouter(cfg, RuntimeContext{Env: "prod"})
+
+	for path, expected := range map[string]string{
+		"/old/%2Fevil.com":    "/evil.com",
+		"/old/%5C%5Cevil.com": "/%5C%5Cevil.com",
+		"/old/a%20b/c":        "/a%20b/c",
+		"/old/docs/intro?x=1": "/docs/intro?x=1",
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if loc := rec.Header().Get("Location"); loc != expected {
+			t.Errorf("%s: expected Location %q, got %q", path, expected, loc)
+		}
+	}
+}
+
+func TestCompilePattern(t *testing.T) {
+	pattern, err := compilePattern("/blog/_slug")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !pattern.MatchString("blog/first") || pattern.MatchString("blog/first/more") {
+		t.Errorf("unexpected pattern %s", pattern)
+	}
+
+	if _, err := compilePattern("/f/_...rest/g"); err == nil {
+		t.Error("expected invalid source to fail")
+	}
+}
+
+func TestCompileRules_SkipsInvalidRules(t *testing.T) {
+	rules := compileRules(
+		[]RedirectRule{
+			{Source: "/a", Destination: "/b"},
+			{Source: "/c", Destination: "/d", Status: 303},
+			{Source: "/e", Destination: ""},
+			{Source: "/f/_...rest/g", Destination: "/h"},
+			{Source: "/i", Destination: "/j", Status: 301},
+		},
+		[]RewriteRule{
+			{Source: "/k", Destination: "/l"},
+			{Source: "/m", Destination: "https://example.com"},
+		},
+		nil,
+	)
+
+	if len(rules.redirects) != 2 {
+		t.Fatalf("expected 2 valid redirects, got %d", len(rules.redirects))
+	}
+	if rules.redirects[0].Status != http.StatusPermanentRedirect {
+		t.Errorf("expected default status 308, got %d", rules.redirects[0].Status)
+	}
+	if rules.redirects[1].Status != http.StatusMovedPermanently {
+		t.Errorf("expected status 301, got %d", rules.redirects[1].Status)
+	}
+	if len(rules.rewrites) != 1 {
+		t.Errorf("expected 1 valid rewrite, got %d", len(rules.rewrites))
+	}
+}
+
+func TestRouter_RedirectBeforeRouteMatching(t *testing.T) {
+	cfg, cleanup := setupRouterTestEnv(t)
+	defer cleanup()
+
+	cfg.Redirects = []RedirectRule{
+		{Source: "/test", Destination: "/new-test", Status: 301},
+		{Source: "/old-blog/_slug", Destination: "/blog/_slug", Status: 307},
+	}
+	router := NewRouter(cfg, RuntimeContext{Env: "prod"})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/new-test" {
+		t.Errorf("expected 301 to /new-test, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/old-blog/first-post?utm=x", nil))
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Errorf("expected 307, got %d", rec.Code)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/blog/first-post?utm=x" {
+		t.Errorf("expected placeholder and query substitution, got %q", loc)
+	}
+}
+
+func TestRouter_RewriteServesTargetRoute(t *testing.T) {
+	cfg, cleanup := setupRouterTestEnv(t)
+	defer cleanup()
+
+	cfg.Rewrites = []RewriteRule{{Source: "/alias/_name", Destination: "/test?from=_name"}}
+	router := NewRouter(cfg, RuntimeContext{Env: "prod"})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/alias/anything", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec.Header().Get("Location") != "" {
+		t.Error("rewrite should not redirect")
+	}
+	if !strings.Contains(rec.Body.String(), "Hello") {
+		t.Errorf("expected target route content, got: %s", rec.Body.String())
+	}
+}
+
+func TestRewriteRequest_MergesQuery(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/shop?sort=asc&category=user", nil)
+
+	rewritten, path := rewriteRequest(req, "/products/?category=shop&view=grid#ignored")
+
+	if path != "products" {
+		t.Errorf("expected path products, got %s", path)
+	}
+	query := rewritten.URL.Query()
+	if query.Get("sort") != "asc" || query.Get("category") != "user" || query.Get("view") != "grid" {
+		t.Errorf("unexpected query: %v", query)
+	}
+	if req.URL.Query().Get("view") != "" {
+		t.Error("original request should not be modified")
+	}
+}
+
+func TestLoadRules_KeepsCallerConfig(t *testing.T) {
+	chdirTemp(t)
+	_ = os.WriteFile(configFile, []byte("redirects:\n  - source: /from-file\n    destination: /x\n"), 0644)
+
+	r := &Router{}
+	r.setRules([]RedirectRule{{Source: "/old", Destination: "/new"}}, nil)
+	r.loadRules()
+
+	if _, _, ok := r.matchRedirect("old"); !ok {
+		t.Error("expected redirect passed to NewRouter to survive a reload")
+	}
+	if _, _, ok := r.matchRedirect("from-file"); ok {
+		t.Error("expected barry.config.yml to be ignored when the config was passed in")
+	}
+}
+
+func TestLoadRules_ReloadsConfigFile(t *testing.T) {
+	chdirTemp(t)
+	_ = os.WriteFile(configFile, []byte("redirects:\n  - source: /from-file\n    destination: /x\n"), 0644)
+
+	r := &Router{configFile: configFile}
+	r.setRules([]RedirectRule{{Source: "/old", Destination: "/new"}}, nil)
+	r.loadRules()
+
+	if _, _, ok := r.matchRedirect("from-file"); !ok {
+		t.Error("expected redirects to be re-read from barry.config.yml")
+	}
+	if _, _, ok := r.matchRedirect("old"); ok {
+		t.Error("expected old redirects to be replaced")
+	}
+}
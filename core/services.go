@@ -0,0 +1,33 @@
+package core
+
+import (
+	"context"
+	"net/http"
+)
+
+type servicesKey struct{}
+
+func WithServices(ctx context.Context, services map[string]interface{}) context.Context {
+	if len(services) == 0 {
+		return ctx
+	}
+	merged := map[string]interface{}{}
+	if existing, ok := ctx.Value(servicesKey{}).(map[string]interface{}); ok {
+		for name, svc := range existing {
+			merged[name] = svc
+		}
+	}
+	for name, svc := range services {
+		merged[name] = svc
+	}
+	return context.WithValue(ctx, servicesKey{}, merged)
+}
+
+func Service(req *http.Request, name string) (interface{}, bool) {
+	if req == nil {
+		return nil, false
+	}
+	services, _ := req.Context().Value(servicesKey{}).(map[string]interface{})
+	svc, ok := services[name]
+	return svc, ok
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithServices_MergesIntoContext(t *testing.T) {
+	ctx := WithServices(context.Background(), map[string]interface{}{"db": "pool", "region": "eu"})
+	ctx = WithServices(ctx, map[string]interface{}{"region": "us"})
+	if same := WithServices(ctx, nil); same != ctx {
+		t.Error("expected empty services to leave the context alone")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	if svc, ok := Service(req, "db"); !ok || svc != "pool" {
+		t.Errorf("expected db service, got %v", svc)
+	}
+	if svc, _ := Service(req, "region"); svc != "us" {
+		t.Errorf("expected later services to win, got %v", svc)
+	}
+	if _, ok := Service(req, "cache"); ok {
+		t.Error("expected missing service")
+	}
+	if _, ok := Service(nil, "db"); ok {
+		t.Error("expected nil request to have no services")
+	}
+}
+
+func TestServeHTTP_HandlersSeeServices(t *testing.T) {
+	setupExecSite(t)
+
+	var got interface{}
+	original := ExecuteServerFile
+	ExecuteServerFile = func(_ string, req *http.Request, _ map[string]string) (map[string]interface{}, error) {
+		got, _ = Service(req, "db")
+		return nil, nil
+	}
+	t.Cleanup(func() { ExecuteServerFile = original })
+
+	router := NewRouter(Config{OutputDir: "cache"}, RuntimeContext{Env: "prod", Services: map[string]interface{}{"db": "pool"}})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	if got != "pool" {
+		t.Errorf("expected handler to see the db service, got %v", got)
+	}
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+func SiteFileExists(fsys fs.FS, name string) bool {
+	_, err := siteStat(fsys, name)
+	return err == nil
+}
+
+func sitePath(fsys fs.FS, name string) (string, bool) {
+	if fsys == nil || filepath.IsAbs(name) {
+		return "", false
+	}
+	clean := filepath.ToSlash(filepath.Clean(name))
+	return clean, fs.ValidPath(clean)
+}
+
+func siteReadFile(fsys fs.FS, name string) ([]byte, error) {
+	if path, ok := sitePath(fsys, name); ok {
+		return fs.ReadFile(fsys, path)
+	}
+	return os.ReadFile(name)
+}
+
+func siteStat(fsys fs.FS, name string) (fs.FileInfo, error) {
+	if path, ok := sitePath(fsys, name); ok {
+		return fs.Stat(fsys, path)
+	}
+	return os.Stat(name)
+}
+
+func siteReadDir(fsys fs.FS, name string) ([]fs.DirEntry, error) {
+	if path, ok := sitePath(fsys, name); ok {
+		return fs.ReadDir(fsys, path)
+	}
+	return os.ReadDir(name)
+}
+
+func siteOpen(fsys fs.FS, name string) (fs.File, error) {
+	if path, ok := sitePath(fsys, name); ok {
+		return fsys.Open(path)
+	}
+	return os.Open(name)
+}
+
+func siteWalkDir(fsys fs.FS, root string, fn fs.WalkDirFunc) error {
+	path, ok := sitePath(fsys, root)
+	if !ok {
+		return filepath.WalkDir(root, fn)
+	}
+	return fs.WalkDir(fsys, path, func(path string, d fs.DirEntry, err error) error {
+		return fn(filepath.FromSlash(path), d, err)
+	})
+}
+
+func parseSiteFiles(fsys fs.FS, t *template.Template, files ...string) (*template.Template, error) {
+	for _, file := range files {
+		data, err := siteReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.Base(file)
+		tmpl := t
+		if name != t.Name() {
+			tmpl = t.New(name)
+		}
+		if _, err := tmpl.Parse(string(data)); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func ServeSiteFile(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string) {
+	if path, ok := sitePath(fsys, name); ok {
+		http.ServeFileFS(w, r, fsys, path)
+		return
+	}
+	http.ServeFile(w, r, name)
+}
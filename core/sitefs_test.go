@@ -0,0 +1,167 @@
+package core
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	tmp := t.TempDir()
+	wd, _ := os.Getwd()
+	_ = os.Chdir(tmp)
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+}
+
+func TestRouter_ServesSiteFromFS(t *testing.T) {
+	chdirTemp(t)
+	site := fstest.MapFS{
+		"barry.config.yml":                  {Data: []byte("layoutKey: Site\n")},
+		"routes/index.html":                 {Data: []byte("<!-- layout: components/layouts/base.html -->\n{{ define \"content\" }}home{{ end }}")},
+		"routes/blog/_slug/index.html":      {Data: []byte("<!-- layout: components/layouts/base.html -->\n{{ define \"content\" }}{{ .Slug }}{{ end }}")},
+		"routes/blog/_slug/index.server.go": {Data: []byte("package slug\n\nfunc HandleRequest() {}\n")},
+		"components/layouts/base.html":      {Data: []byte(`{{ define "layout" }}<main>{{ template "content" . }}</main>{{ end }}`)},
+		"api/ping/get.go":                   {Data: []byte("package ping\n\nfunc HandleGet() {}\n")},
+	}
+
+	original := ExecuteServerFile
+	ExecuteServerFile = func(path string, req *http.Request, params map[string]string) (map[string]interface{}, error) {
+		return map[string]interface{}{"Slug": params["slug"]}, nil
+	}
+	originalAPI := ExecuteAPIFile
+	ExecuteAPIFile = func(path string, req *http.Request, params map[string]string) ([]byte, error) {
+		return []byte(`{"pong":"` + HandlerName(req) + `"}`), nil
+	}
+	t.Cleanup(func() {
+		ExecuteServerFile = original
+		ExecuteAPIFile = originalAPI
+	})
+
+	config := LoadConfigFS(site, "barry.config.yml")
+	if config.LayoutKey != "Site" {
+		t.Errorf("expected config read from the site FS, got %+v", config)
+	}
+
+	config.OutputDir = "cache"
+	router := NewRouter(*config, RuntimeContext{Env: "prod", FS: site})
+
+	for path, want := range map[string]string{
+		"/":          "<main>home</main>",
+		"/blog/post": "<main>post</main>",
+		"/api/ping":  `"pong":"HandleGet"`,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("%s: expected %q, got %d %q", path, want, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestSiteReadFile_AbsolutePathsUseDisk(t *testing.T) {
+	chdirTemp(t)
+	site := fstest.MapFS{"public/a.txt": {Data: []byte("embedded")}}
+
+	abs := filepath.Join(t.TempDir(), "a.txt")
+	_ = os.WriteFile(abs, []byte("disk"), 0644)
+	_ = os.MkdirAll("public", 0755)
+	_ = os.WriteFile("public/b.txt", []byte("disk"), 0644)
+
+	if data, err := siteReadFile(site, "public/a.txt"); err != nil || string(data) != "embedded" {
+		t.Errorf("expected embedded file, got %q %v", data, err)
+	}
+	if data, err := siteReadFile(site, abs); err != nil || string(data) != "disk" {
+		t.Errorf("expected disk file, got %q %v", data, err)
+	}
+	if SiteFileExists(site, "public/b.txt") {
+		t.Error("expected missing embedded file")
+	}
+	if !SiteFileExists(nil, "public/b.txt") {
+		t.Error("expected nil FS to read from disk")
+	}
+}
+
+func TestParseSiteFiles_NamesTemplatesByBase(t *testing.T) {
+	site := fstest.MapFS{
+		"components/a.html": {Data: []byte(`{{ define "a" }}A{{ end }}`)},
+		"routes/index.html": {Data: []byte(`{{ template "a" }}!`)},
+	}
+
+	tmpl, err := parseSiteFiles(site, template.New(""), "components/a.html", "routes/index.html")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var b strings.Builder
+	if err := tmpl.ExecuteTemplate(&b, "index.html", nil); err != nil || b.String() != "A!" {
+		t.Errorf("unexpected render %q %v", b.String(), err)
+	}
+
+	if _, err := parseSiteFiles(site, template.New(""), "routes/missing.html"); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestRouter_ReloadAndClose(t *testing.T) {
+	site := fstest.MapFS{
+		"routes/index.html":            {Data: []byte("<!-- layout: components/layouts/base.html -->\n{{ define \"content\" }}home{{ end }}")},
+		"components/layouts/base.html": {Data: []byte(`{{ define "layout" }}<main>{{ template "content" . }}</main>{{ end }}`)},
+	}
+	chdirTemp(t)
+
+	var logs strings.Builder
+	router := NewRouter(Config{OutputDir: "cache"}, RuntimeContext{Env: "prod", FS: site, Logger: &logs}).(*Router)
+
+	site["routes/about/index.html"] = &fstest.MapFile{Data: []byte("<!-- layout: components/layouts/base.html -->\n{{ define \"content\" }}about{{ end }}")}
+	router.Reload()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/about", nil))
+	if rec.Body.String() != "<main>about</main>" {
+		t.Errorf("expected reloaded route, got %q", rec.Body.String())
+	}
+
+	if err := router.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := router.Close(); err != nil {
+		t.Errorf("unexpected error on second close: %v", err)
+	}
+}
+
+func TestRouter_EachRouterKeepsItsOwnFSAndWorkers(t *testing.T) {
+	chdirTemp(t)
+	page := func(body string) fstest.MapFS {
+		return fstest.MapFS{"routes/index.html": {Data: []byte(body)}}
+	}
+
+	first := NewRouter(Config{OutputDir: "cache"}, RuntimeContext{Env: "prod", FS: page("first")}).(*Router)
+	second := NewRouter(Config{OutputDir: "cache"}, RuntimeContext{Env: "prod", FS: page("second")}).(*Router)
+
+	for router, want := range map[*Router]string{first: "first", second: "second"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if rec.Body.String() != want {
+			t.Errorf("expected %q, got %q", want, rec.Body.String())
+		}
+	}
+
+	first.workers.poolFor("a.go")
+	second.workers.poolFor("a.go")
+	_ = first.Close()
+
+	if _, ok := first.workers.pools.Load("a.go"); ok {
+		t.Error("expected closed router to stop its workers")
+	}
+	if _, ok := second.workers.pools.Load("a.go"); !ok {
+		t.Error("expected other router to keep its workers")
+	}
+	if first.workers.id == second.workers.id {
+		t.Error("expected routers to use separate worker run dirs")
+	}
+}
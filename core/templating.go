@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"html/template"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -18,6 +19,10 @@ import (
 )
 
 func MinifyAsset(env, path string, cacheDir string) string {
+	return minifyAsset(nil, env, path, cacheDir)
+}
+
+func minifyAsset(fsys fs.FS, env, path string, cacheDir string) string {
 	if env != "prod" {
 		return path
 	}
@@ -39,7 +44,7 @@ func MinifyAsset(env, path string, cacheDir string) string {
 	min := filepath.Join(cacheDir, "static", fmt.Sprintf("%s.min%s", name, ext))
 	minGz := min + ".gz"
 
-	original, err := os.ReadFile(src)
+	original, err := siteReadFile(fsys, src)
 	if err != nil {
 		return path
 	}
@@ -90,10 +95,21 @@ func MinifyAsset(env, path string, cacheDir string) string {
 }
 
 func BarryTemplateFuncs(env, cacheDir string) template.FuncMap {
+	return BarryTemplateFuncsWithConfig(env, Config{OutputDir: cacheDir})
+}
+
+func BarryTemplateFuncsWithConfig(env string, config Config) template.FuncMap {
+	return siteTemplateFuncs(nil, env, config)
+}
+
+func siteTemplateFuncs(fsys fs.FS, env string, config Config) template.FuncMap {
 	funcs := sprig.HtmlFuncMap()
+	cacheDir := config.OutputDir
+	basePath := NormalizeBasePath(config.BasePath)
+	urls := newURLBuilder(fsys, config)
 
 	funcs["minify"] = func(path string) string {
-		return MinifyAsset(env, path, cacheDir)
+		return withBasePath(basePath, minifyAsset(fsys, env, stripBasePath(basePath, path), cacheDir))
 	}
 
 	funcs["props"] = func(values ...interface{}) map[string]interface{} {
@@ -111,6 +127,10 @@ func BarryTemplateFuncs(env, cacheDir string) template.FuncMap {
 		return m
 	}
 
+	funcs["component"] = func(name string, props ...map[string]interface{}) (template.HTML, error) {
+		return "", fmt.Errorf("component %q can only be rendered while serving a request", name)
+	}
+
 	funcs["safeHTML"] = func(s interface{}) template.HTML {
 		switch val := s.(type) {
 		case template.HTML:
@@ -122,27 +142,33 @@ func BarryTemplateFuncs(env, cacheDir string) template.FuncMap {
 		}
 	}
 
+	funcs["url"] = func(route string, args ...interface{}) (string, error) {
+		params, err := urlParams(args)
+		if err != nil {
+			return "", err
+		}
+		return urls.build(route, params)
+	}
+
 	funcs["versioned"] = func(path string) string {
+		path = stripBasePath(basePath, path)
 		if !strings.HasPrefix(path, "/static/") {
-			return path
+			return withBasePath(basePath, path)
 		}
 
 		rel := strings.TrimPrefix(path, "/static/")
-		locations := []string{
-			filepath.Join("public", rel),
-			filepath.Join(cacheDir, "static", rel),
+		content, err := siteReadFile(fsys, filepath.Join("public", rel))
+		if err != nil {
+			content, err = os.ReadFile(filepath.Join(cacheDir, "static", rel))
 		}
-
-		for _, file := range locations {
-			if content, err := os.ReadFile(file); err == nil {
-				h := md5.New()
-				h.Write(content)
-				hash := hex.EncodeToString(h.Sum(nil))[:6]
-				return fmt.Sprintf("/static/%s?v=%s", rel, hash)
-			}
+		if err == nil {
+			h := md5.New()
+			h.Write(content)
+			hash := hex.EncodeToString(h.Sum(nil))[:6]
+			return fmt.Sprintf("%s/static/%s?v=%s", basePath, rel, hash)
 		}
 
-		return path
+		return withBasePath(basePath, path)
 	}
 
 	return funcs
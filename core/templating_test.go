@@ -271,3 +271,22 @@ func TestBarryTemplateFuncs_versionedSkipsNonStatic(t *testing.T) {
 		t.Errorf("expected original path, got %s", result)
 	}
 }
+
+func TestBarryTemplateFuncs_prefixesBasePath(t *testing.T) {
+	funcs := BarryTemplateFuncsWithConfig("dev", Config{OutputDir: t.TempDir(), BasePath: "/docs"})
+	versioned := funcs["versioned"].(func(string) string)
+	minifyFunc := funcs["minify"].(func(string) string)
+
+	if got := versioned("/static/missing.css"); got != "/docs/static/missing.css" {
+		t.Errorf("expected prefixed versioned path, got %s", got)
+	}
+	if got := versioned("/docs/static/missing.css"); got != "/docs/static/missing.css" {
+		t.Errorf("expected already prefixed path to be kept, got %s", got)
+	}
+	if got := minifyFunc("/static/app.js"); got != "/docs/static/app.js" {
+		t.Errorf("expected prefixed minify path, got %s", got)
+	}
+	if got := versioned("https://cdn.example.com/app.js"); got != "https://cdn.example.com/app.js" {
+		t.Errorf("expected absolute URL to be untouched, got %s", got)
+	}
+}
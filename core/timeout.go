@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+const defaultExecTimeout = 30 * time.Second
+
+type timeoutRule struct {
+	pattern *regexp.Regexp
+	timeout time.Duration
+}
+
+type timeoutSet struct {
+	global time.Duration
+	rules  []timeoutRule
+}
+
+func parseExecTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultExecTimeout, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("timeout must not be negative")
+	}
+	return d, nil
+}
+
+func compileTimeouts(global string, rules []TimeoutRule, out io.Writer) *timeoutSet {
+	timeouts := &timeoutSet{global: defaultExecTimeout}
+
+	if d, err := parseExecTimeout(global); err != nil {
+		logf(out, "⚠️ Invalid timeout %q: %v, using %s\n", global, err, defaultExecTimeout)
+	} else {
+		timeouts.global = d
+	}
+
+	for _, rule := range rules {
+		d, err := parseExecTimeout(rule.Timeout)
+		if err == nil && rule.Timeout == "" {
+			err = fmt.Errorf("missing timeout")
+		}
+		var pattern *regexp.Regexp
+		if err == nil {
+			pattern, err = compilePattern(rule.Source)
+		}
+		if err != nil {
+			logf(out, "⚠️ Skipping timeout %s: %v\n", rule.Source, err)
+			continue
+		}
+		timeouts.rules = append(timeouts.rules, timeoutRule{pattern: pattern, timeout: d})
+	}
+
+	return timeouts
+}
+
+func (r *Router) setTimeouts(global string, rules []TimeoutRule) {
+	timeouts := compileTimeouts(global, rules, r.logger)
+
+	r.routesMu.Lock()
+	r.timeouts = timeouts
+	r.routesMu.Unlock()
+}
+
+func (r *Router) execTimeout(path string) time.Duration {
+	r.routesMu.RLock()
+	defer r.routesMu.RUnlock()
+
+	if r.timeouts == nil {
+		return defaultExecTimeout
+	}
+	for _, rule := range r.timeouts.rules {
+		if rule.pattern.MatchString(path) {
+			return rule.timeout
+		}
+	}
+	return r.timeouts.global
+}
+
+func (r *Router) withExecTimeout(req *http.Request, path string) (*http.Request, context.CancelFunc) {
+	timeout := r.execTimeout(path)
+	if timeout <= 0 {
+		return req, func() {}
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	return req.WithContext(ctx), cancel
+}
+
+func IsTimeoutError(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileTimeouts(t *testing.T) {
+	timeouts := compileTimeouts("", nil, nil)
+	if timeouts.global != defaultExecTimeout {
+		t.Errorf("expected default timeout, got %s", timeouts.global)
+	}
+
+	timeouts = compileTimeouts("nope", nil, nil)
+	if timeouts.global != defaultExecTimeout {
+		t.Errorf("expected invalid timeout to fall back to default, got %s", timeouts.global)
+	}
+
+	timeouts = compileTimeouts("5s", []TimeoutRule{
+		{Source: "/products/_id", Timeout: "0"},
+		{Source: "/api/slow", Timeout: "1m"},
+		{Source: "/broken", Timeout: "soon"},
+		{Source: "/empty"},
+		{Source: "/bad/_...rest/more", Timeout: "1s"},
+	}, nil)
+	if timeouts.global != 5*time.Second {
+		t.Errorf("expected 5s, got %s", timeouts.global)
+	}
+	if len(timeouts.rules) != 2 {
+		t.Fatalf("expected 2 valid rules, got %d", len(timeouts.rules))
+	}
+
+	r := &Router{timeouts: timeouts}
+	cases := map[string]time.Duration{
+		"products/42": 0,
+		"api/slow":    time.Minute,
+		"about":       5 * time.Second,
+	}
+	for path, want := range cases {
+		if got := r.execTimeout(path); got != want {
+			t.Errorf("%s: expected %s, got %s", path, want, got)
+		}
+	}
+}
+
+func TestWithExecTimeout_ZeroDisablesDeadline(t *testing.T) {
+	r := &Router{timeouts: compileTimeouts("0", nil, nil)}
+	req, cancel := r.withExecTimeout(httptest.NewRequest(http.MethodGet, "/", nil), "")
+	defer cancel()
+
+	if _, ok := req.Context().Deadline(); ok {
+		t.Error("expected no deadline when timeout is 0")
+	}
+
+	r = &Router{timeouts: compileTimeouts("1s", nil, nil)}
+	req, cancel = r.withExecTimeout(httptest.NewRequest(http.MethodGet, "/", nil), "")
+	defer cancel()
+
+	if _, ok := req.Context().Deadline(); !ok {
+		t.Error("expected a deadline")
+	}
+}
+
+func setupExecSite(t *testing.T) {
+	t.Helper()
+	tmp := t.TempDir()
+	wd, _ := os.Getwd()
+	_ = os.Chdir(tmp)
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	files := map[string]string{
+		"routes/slow/index.html":       "<!-- layout: components/layouts/base.html -->\n{{ define \"content\" }}slow{{ end }}",
+		"routes/slow/index.server.go":  "package slow",
+		"routes/_error/index.html":     "<!-- layout: components/layouts/base.html -->\n{{ define \"content\" }}<h1>Error {{ .StatusCode }}: {{ .Message }}</h1>{{ end }}",
+		"api/slow/index.go":            "package slow",
+		"components/layouts/base.html": `{{ define "layout" }}{{ template "content" . }}{{ end }}`,
+	}
+	for path, content := range files {
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		_ = os.WriteFile(path, []byte(content), 0644)
+	}
+}
+
+func blockUntilDone(t *testing.T) {
+	t.Helper()
+	originalServer := ExecuteServerFile
+	originalAPI := ExecuteAPIFile
+	ExecuteServerFile = func(_ string, req *http.Request, _ map[string]string) (map[string]interface{}, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	ExecuteAPIFile = func(_ string, req *http.Request, _ map[string]string) ([]byte, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	t.Cleanup(func() {
+		ExecuteServerFile = originalServer
+		ExecuteAPIFile = originalAPI
+	})
+}
+
+func TestServeHTTP_PageTimeoutRenders504(t *testing.T) {
+	setupExecSite(t)
+	blockUntilDone(t)
+
+	router := NewRouter(Config{OutputDir: "cache", Timeout: "20ms"}, RuntimeContext{Env: "dev"})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "<h1>Error 504: Gateway Timeout</h1>") {
+		t.Errorf("expected rendered error page, got %q", rec.Body.String())
+	}
+}
+
+func TestServeHTTP_APIRouteTimeoutOverridesGlobal(t *testing.T) {
+	setupExecSite(t)
+	blockUntilDone(t)
+
+	router := NewRouter(Config{
+		OutputDir: "cache",
+		Timeout:   "1h",
+		Timeouts:  []TimeoutRule{{Source: "/api/slow", Timeout: "20ms"}},
+	}, RuntimeContext{Env: "dev"})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/slow", nil))
+		done <- rec
+	}()
+
+	select {
+	case rec := <-done:
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Errorf("expected 504, got %d", rec.Code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("route timeout was not applied")
+	}
+}
+
+func TestServeHTTP_ClientDisconnectWritesNothing(t *testing.T) {
+	setupExecSite(t)
+	blockUntilDone(t)
+
+	router := NewRouter(Config{OutputDir: "cache"}, RuntimeContext{Env: "dev"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil).WithContext(ctx))
+
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected no body for a canceled request, got %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,209 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+type urlBuilder struct {
+	fsys          fs.FS
+	basePath      string
+	trailingSlash string
+	strict        bool
+}
+
+type urlBuilderKey struct{}
+
+func newURLBuilder(fsys fs.FS, config Config) urlBuilder {
+	return urlBuilder{fsys: fsys, basePath: NormalizeBasePath(config.BasePath), trailingSlash: config.TrailingSlash, strict: true}
+}
+
+func withURLBuilder(ctx context.Context, fsys fs.FS, config Config) context.Context {
+	return context.WithValue(ctx, urlBuilderKey{}, newURLBuilder(fsys, config))
+}
+
+func URLFor(req *http.Request, route string, params map[string]string) (string, error) {
+	var builder urlBuilder
+	ok := false
+	if req != nil {
+		builder, ok = req.Context().Value(urlBuilderKey{}).(urlBuilder)
+	}
+	if !ok {
+		builder = newURLBuilder(nil, *LoadConfig(configFile))
+	}
+
+	values := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		values[k] = v
+	}
+	return builder.build(route, values)
+}
+
+func ValidateURL(route string, keys []string) error {
+	params := map[string]interface{}{}
+	for _, key := range keys {
+		params[key] = nil
+	}
+
+	builder := urlBuilder{strict: false}
+	if keys == nil {
+		_, err := builder.resolve(route)
+		return err
+	}
+	_, err := builder.build(route, params)
+	return err
+}
+
+func CheckTemplateFuncs() template.FuncMap {
+	funcs := BarryTemplateFuncs("dev", "cache")
+	builder := urlBuilder{strict: false}
+
+	funcs["url"] = func(route string, args ...interface{}) (string, error) {
+		params, _ := urlParams(args)
+		path, _ := builder.build(route, params)
+		return path, nil
+	}
+
+	funcs["component"] = func(name string, props ...map[string]interface{}) (template.HTML, error) {
+		return "", nil
+	}
+
+	return funcs
+}
+
+func (b urlBuilder) resolve(route string) (string, error) {
+	id := strings.Trim(filepath.ToSlash(route), "/")
+	if id == "index" {
+		id = ""
+	}
+
+	if id == "api" || strings.HasPrefix(id, "api/") {
+		if findApiFile(b.fsys, id) == "" {
+			return "", fmt.Errorf("unknown route %q", route)
+		}
+		return id, nil
+	}
+
+	dir := filepath.Join("routes", id)
+	if !SiteFileExists(b.fsys, filepath.Join(dir, "index.html")) && !SiteFileExists(b.fsys, filepath.Join(dir, "index.xml")) {
+		return "", fmt.Errorf("unknown route %q", route)
+	}
+	return id, nil
+}
+
+func (b urlBuilder) build(route string, params map[string]interface{}) (string, error) {
+	id, err := b.resolve(route)
+	if err != nil {
+		return "", err
+	}
+
+	segments, err := parseRouteSegments(id)
+	if err != nil {
+		return "", fmt.Errorf("route %q: %w", route, err)
+	}
+
+	used := map[string]bool{}
+	parts := []string{}
+
+	for _, seg := range segments {
+		if seg.Kind == segmentStatic {
+			parts = append(parts, url.PathEscape(seg.Value))
+			continue
+		}
+
+		used[seg.Value] = true
+		raw, ok := params[seg.Value]
+		value := ""
+		if raw != nil {
+			value = fmt.Sprint(raw)
+		}
+
+		if !ok && !seg.isOptional() {
+			return "", fmt.Errorf("route %q: missing param %q", route, seg.Value)
+		}
+		if value == "" {
+			if seg.isOptional() {
+				continue
+			}
+			if b.strict {
+				return "", fmt.Errorf("route %q: empty param %q", route, seg.Value)
+			}
+			value = seg.Value
+		}
+
+		if seg.isCatchAll() {
+			escaped := []string{}
+			for _, part := range strings.Split(strings.Trim(value, "/"), "/") {
+				escaped = append(escaped, url.PathEscape(part))
+			}
+			parts = append(parts, strings.Join(escaped, "/"))
+			continue
+		}
+
+		parts = append(parts, url.PathEscape(value)+seg.Ext)
+	}
+
+	path := "/" + strings.Join(parts, "/")
+	isAPI := id == "api" || strings.HasPrefix(id, "api/")
+	if !isAPI && b.trailingSlash == TrailingSlashAlways && path != "/" && !strings.Contains(parts[len(parts)-1], ".") {
+		path += "/"
+	}
+
+	if b.basePath != "" {
+		if path == "/" && b.trailingSlash != TrailingSlashAlways {
+			path = ""
+		}
+		path = b.basePath + path
+	}
+
+	query := url.Values{}
+	for key, value := range params {
+		if used[key] || value == nil {
+			continue
+		}
+		query.Set(key, fmt.Sprint(value))
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	return path, nil
+}
+
+func urlParams(args []interface{}) (map[string]interface{}, error) {
+	params := map[string]interface{}{}
+
+	if len(args) == 1 {
+		switch m := args[0].(type) {
+		case map[string]string:
+			for k, v := range m {
+				params[k] = v
+			}
+			return params, nil
+		case map[string]interface{}:
+			for k, v := range m {
+				params[k] = v
+			}
+			return params, nil
+		}
+	}
+
+	if len(args)%2 != 0 {
+		return nil, fmt.Errorf("url expects key/value pairs or a map of params")
+	}
+	for i := 0; i < len(args); i += 2 {
+		key, ok := args[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("url param keys must be strings")
+		}
+		params[key] = args[i+1]
+	}
+
+	return params, nil
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupURLRoutes(t *testing.T) {
+	t.Cleanup(func() {
+		cleanupTestArtifacts()
+		_ = os.RemoveAll("api")
+	})
+
+	for _, dir := range []string{"routes", "routes/blog/_slug", "routes/docs/_...path", "routes/list/_[page]", "routes/feed/_name.xml"} {
+		_ = os.MkdirAll(dir, 0755)
+		_ = os.WriteFile(filepath.Join(dir, "index.html"), []byte("x"), 0644)
+	}
+	_ = os.MkdirAll("api/users/_id", 0755)
+	_ = os.WriteFile("api/users/_id/get.go", []byte("package users"), 0644)
+}
+
+func TestURLBuilder_Build(t *testing.T) {
+	setupURLRoutes(t)
+
+	b := urlBuilder{strict: true}
+	cases := []struct {
+		route    string
+		params   map[string]interface{}
+		expected string
+	}{
+		{"/", nil, "/"},
+		{"index", nil, "/"},
+		{"blog/_slug", map[string]interface{}{"slug": "hello world"}, "/blog/hello%20world"},
+		{"/blog/_slug/", map[string]interface{}{"slug": "a", "ref": "home"}, "/blog/a?ref=home"},
+		{"docs/_...path", map[string]interface{}{"path": "guide/intro"}, "/docs/guide/intro"},
+		{"list/_[page]", nil, "/list"},
+		{"list/_[page]", map[string]interface{}{"page": 2}, "/list/2"},
+		{"feed/_name.xml", map[string]interface{}{"name": "rss"}, "/feed/rss.xml"},
+		{"api/users/_id", map[string]interface{}{"id": 7}, "/api/users/7"},
+	}
+
+	for _, c := range cases {
+		got, err := b.build(c.route, c.params)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.route, err)
+			continue
+		}
+		if got != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.route, c.expected, got)
+		}
+	}
+}
+
+func TestURLBuilder_Errors(t *testing.T) {
+	setupURLRoutes(t)
+
+	b := urlBuilder{strict: true}
+	if _, err := b.build("missing", nil); err == nil || !strings.Contains(err.Error(), "unknown route") {
+		t.Errorf("expected unknown route error, got %v", err)
+	}
+	if _, err := b.build("blog/_slug", nil); err == nil || !strings.Contains(err.Error(), `missing param "slug"`) {
+		t.Errorf("expected missing param error, got %v", err)
+	}
+	if _, err := b.build("blog/_slug", map[string]interface{}{"slug": ""}); err == nil {
+		t.Error("expected empty param error in strict mode")
+	}
+
+	if err := ValidateURL("blog/_slug", []string{"slug"}); err != nil {
+		t.Errorf("expected key-only validation to pass, got %v", err)
+	}
+	if err := ValidateURL("blog/_slug", nil); err != nil {
+		t.Errorf("expected existence-only validation to pass, got %v", err)
+	}
+}
+
+func TestURLBuilder_BasePathAndTrailingSlash(t *testing.T) {
+	setupURLRoutes(t)
+
+	b := urlBuilder{basePath: "/docs", trailingSlash: TrailingSlashAlways, strict: true}
+	if got, _ := b.build("blog/_slug", map[string]interface{}{"slug": "a"}); got != "/docs/blog/a/" {
+		t.Errorf("expected /docs/blog/a/, got %s", got)
+	}
+	if got, _ := b.build("api/users/_id", map[string]interface{}{"id": "1"}); got != "/docs/api/users/1" {
+		t.Errorf("expected API URL without trailing slash, got %s", got)
+	}
+
+	b = urlBuilder{basePath: "/docs", strict: true}
+	if got, _ := b.build("/", nil); got != "/docs" {
+		t.Errorf("expected /docs for root, got %s", got)
+	}
+}
+
+func TestURLFor_UsesRequestConfig(t *testing.T) {
+	setupURLRoutes(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(withURLBuilder(req.Context(), nil, Config{BasePath: "app", TrailingSlash: TrailingSlashAlways}))
+
+	got, err := URLFor(req, "blog/_slug", map[string]string{"slug": "x"})
+	if err != nil || got != "/app/blog/x/" {
+		t.Errorf("expected /app/blog/x/, got %s (%v)", got, err)
+	}
+}
+
+func TestRouter_URLForUsesRouterConfig(t *testing.T) {
+	setupURLRoutes(t)
+	_ = os.WriteFile("routes/blog/_slug/index.html", []byte(`<!-- layout: layout.html -->
+{{ define "content" }}{{ .Link }}{{ end }}`), 0644)
+	_ = os.WriteFile("routes/blog/_slug/index.server.go", []byte(""), 0644)
+	_ = os.WriteFile("layout.html", []byte(`{{ define "layout" }}{{ template "content" . }}{{ end }}`), 0644)
+
+	original := ExecuteServerFile
+	defer func() { ExecuteServerFile = original }()
+	ExecuteServerFile = func(_ string, req *http.Request, params map[string]string) (map[string]interface{}, error) {
+		link, err := URLFor(req, "blog/_slug", map[string]string{"slug": "next"})
+		return map[string]interface{}{"Link": link}, err
+	}
+
+	router := NewRouter(Config{OutputDir: t.TempDir(), BasePath: "/docs"}, RuntimeContext{Env: "prod"})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/blog/first", nil))
+	if !strings.Contains(rec.Body.String(), "/docs/blog/next") {
+		t.Errorf("expected link with router base path, got %q", rec.Body.String())
+	}
+}
+
+func TestURLFor_FallsBackToConfigFile(t *testing.T) {
+	setupURLRoutes(t)
+
+	original := LoadConfig
+	defer func() { LoadConfig = original }()
+	LoadConfig = func(string) *Config { return &Config{BasePath: "app"} }
+
+	got, err := URLFor(nil, "blog/_slug", map[string]string{"slug": "x"})
+	if err != nil || got != "/app/blog/x" {
+		t.Errorf("expected /app/blog/x, got %s (%v)", got, err)
+	}
+}
+
+func TestBarryTemplateFuncs_url(t *testing.T) {
+	setupURLRoutes(t)
+
+	urlFunc := BarryTemplateFuncsWithConfig("dev", Config{})["url"].(func(string, ...interface{}) (string, error))
+
+	if got, err := urlFunc("blog/_slug", "slug", "x"); err != nil || got != "/blog/x" {
+		t.Errorf("expected /blog/x, got %s (%v)", got, err)
+	}
+	if got, err := urlFunc("blog/_slug", map[string]string{"slug": "y"}); err != nil || got != "/blog/y" {
+		t.Errorf("expected /blog/y, got %s (%v)", got, err)
+	}
+	if _, err := urlFunc("blog/_slug", "slug"); err == nil {
+		t.Error("expected error for odd params")
+	}
+}
+
+func TestCheckTemplateFuncs_ComponentDoesNotFail(t *testing.T) {
+	component := CheckTemplateFuncs()["component"].(func(string, ...map[string]interface{}) (template.HTML, error))
+	if out, err := component("LatestPosts", map[string]interface{}{"limit": 5}); out != "" || err != nil {
+		t.Errorf("expected component to render nothing during checks, got %q, %v", out, err)
+	}
+}
@@ -0,0 +1,379 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	json "github.com/segmentio/encoding/json"
+)
+
+const workerStderrLimit = 64 * 1024
+
+var workers = &workerSet{}
+var workerSetIDs atomic.Int64
+
+type workerSet struct {
+	id     string
+	logger io.Writer
+	pools  sync.Map
+}
+
+type workerSetKey struct{}
+
+type workerRequest struct {
+	Handler       string              `json:"handler"`
+	Method        string              `json:"method"`
+	URL           string              `json:"url"`
+	RequestURI    string              `json:"requestURI"`
+	Proto         string              `json:"proto"`
+	Header        map[string][]string `json:"header"`
+	Host          string              `json:"host"`
+	RemoteAddr    string              `json:"remoteAddr"`
+	TLS           bool                `json:"tls"`
+	ContentLength int64               `json:"contentLength"`
+	Body          []byte              `json:"body"`
+	Params        map[string]string   `json:"params"`
+}
+
+type workerResponse struct {
+	Result   json.RawMessage `json:"result"`
+	Error    string          `json:"error"`
+	NotFound bool            `json:"notFound"`
+	Status   int             `json:"status"`
+	Location string          `json:"location"`
+	Meta     *ResponseMeta   `json:"meta"`
+}
+
+type workerPool struct {
+	setID      string
+	logger     io.Writer
+	lock       chan struct{}
+	binPath    string
+	stamps     map[string]time.Time
+	generation int
+	idle       []*workerProc
+}
+
+type workerProc struct {
+	generation int
+	cmd        *exec.Cmd
+	stdin      io.WriteCloser
+	stdout     *bufio.Reader
+	stderr     *tailBuffer
+	done       chan struct{}
+	exitErr    error
+}
+
+var maxIdleWorkers = runtime.NumCPU()
+
+func newWorkerSet(logger io.Writer) *workerSet {
+	return &workerSet{id: strconv.FormatInt(workerSetIDs.Add(1), 10), logger: logger}
+}
+
+func withWorkers(ctx context.Context, set *workerSet) context.Context {
+	return context.WithValue(ctx, workerSetKey{}, set)
+}
+
+func workersFor(req *http.Request) *workerSet {
+	if set, ok := req.Context().Value(workerSetKey{}).(*workerSet); ok {
+		return set
+	}
+	return workers
+}
+
+func (s *workerSet) poolFor(absPath string) *workerPool {
+	w, _ := s.pools.LoadOrStore(absPath, &workerPool{setID: s.id, logger: s.logger, lock: make(chan struct{}, 1)})
+	return w.(*workerPool)
+}
+
+func workerFor(absPath string) *workerPool {
+	return workers.poolFor(absPath)
+}
+
+func (w *workerPool) acquire(ctx context.Context) error {
+	select {
+	case w.lock <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("exec error: %w", ctx.Err())
+	}
+}
+
+func (w *workerPool) release() {
+	<-w.lock
+}
+
+func StopWorkers() {
+	workers.stop()
+}
+
+func (s *workerSet) stop() {
+	s.pools.Range(func(key, value interface{}) bool {
+		w := value.(*workerPool)
+		_ = w.acquire(context.Background())
+		w.retire()
+		w.release()
+		s.pools.Delete(key)
+		return true
+	})
+}
+
+func (w *workerPool) retire() {
+	for _, proc := range w.idle {
+		proc.stop()
+	}
+	w.idle = nil
+	w.generation++
+}
+
+func (w *workerPool) take(modRoot string) (*workerProc, error) {
+	for len(w.idle) > 0 {
+		proc := w.idle[len(w.idle)-1]
+		w.idle = w.idle[:len(w.idle)-1]
+		if proc.running() {
+			return proc, nil
+		}
+	}
+	return startWorkerProc(w.binPath, modRoot, w.generation, w.logOutput())
+}
+
+func (w *workerPool) logOutput() io.Writer {
+	if w.logger != nil {
+		return w.logger
+	}
+	return os.Stderr
+}
+
+func (w *workerPool) put(proc *workerProc) {
+	_ = w.acquire(context.Background())
+	defer w.release()
+
+	if !proc.running() || proc.generation != w.generation || len(w.idle) >= maxIdleWorkers {
+		proc.stop()
+		return
+	}
+	w.idle = append(w.idle, proc)
+}
+
+func (w *workerPool) stale() bool {
+	if w.binPath == "" || !fileExists(w.binPath) {
+		return true
+	}
+	for path, stamp := range w.stamps {
+		if !modTime(path).Equal(stamp) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *workerPool) build(modRoot, runDir string, files []string) error {
+	binPath := filepath.Join(runDir, fmt.Sprintf("worker-%d", w.generation))
+	if runtime.GOOS == "windows" {
+		binPath += ".exe"
+	}
+
+	args := append([]string{"build", "-o", binPath}, files...)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = modRoot
+
+	var outBuf, errBuf bytes.Buffer
+	cmd.Stdout = &outBuf
+	cmd.Stderr = io.MultiWriter(w.logOutput(), &errBuf)
+
+	if err := cmd.Run(); err != nil {
+		if w.binPath != "" {
+			_ = os.Remove(w.binPath)
+		}
+		w.binPath = ""
+		return fmt.Errorf("exec error: %v\nstderr: %s", err, errBuf.String())
+	}
+
+	if w.binPath != "" && w.binPath != binPath {
+		_ = os.Remove(w.binPath)
+	}
+	w.binPath = binPath
+	w.stamps = workerStamps(modRoot, runDir, files)
+	return nil
+}
+
+func workerStamps(modRoot, runDir string, files []string) map[string]time.Time {
+	stamps := map[string]time.Time{}
+	for _, name := range []string{"go.mod", "go.sum"} {
+		path := filepath.Join(modRoot, name)
+		stamps[path] = modTime(path)
+	}
+
+	args := append([]string{"list", "-deps", "-f", "{{if not .Standard}}{{.Dir}}{{end}}"}, files...)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = modRoot
+	out, err := cmd.Output()
+	if err != nil {
+		return stamps
+	}
+
+	for _, dir := range strings.Split(string(out), "\n") {
+		dir = strings.TrimSpace(dir)
+		if dir == "" || dir == runDir {
+			continue
+		}
+		if rel, err := filepath.Rel(modRoot, dir); err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+
+		stamps[dir] = modTime(dir)
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			if !entry.IsDir() && filepath.Ext(entry.Name()) == ".go" {
+				path := filepath.Join(dir, entry.Name())
+				stamps[path] = modTime(path)
+			}
+		}
+	}
+
+	return stamps
+}
+
+func startWorkerProc(binPath, modRoot string, generation int, logger io.Writer) (*workerProc, error) {
+	cmd := exec.Command(binPath)
+	cmd.Dir = modRoot
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("exec error: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("exec error: %w", err)
+	}
+
+	proc := &workerProc{
+		generation: generation,
+		cmd:        cmd,
+		stdin:      stdin,
+		stdout:     bufio.NewReader(stdout),
+		stderr:     &tailBuffer{limit: workerStderrLimit},
+		done:       make(chan struct{}),
+	}
+	cmd.Stderr = io.MultiWriter(logger, proc.stderr)
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("exec error: %w", err)
+	}
+
+	go func() {
+		proc.exitErr = cmd.Wait()
+		close(proc.done)
+	}()
+
+	return proc, nil
+}
+
+func (w *workerProc) running() bool {
+	select {
+	case <-w.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (w *workerProc) stop() {
+	_ = w.stdin.Close()
+	_ = w.cmd.Process.Kill()
+	<-w.done
+}
+
+func (w *workerProc) call(ctx context.Context, req workerRequest) (workerResponse, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return workerResponse{}, fmt.Errorf("could not encode request: %w", err)
+	}
+
+	type reply struct {
+		line []byte
+		err  error
+	}
+	replies := make(chan reply, 1)
+
+	go func() {
+		_, _ = w.stdin.Write(append(payload, '\n'))
+		line, err := w.stdout.ReadBytes('\n')
+		replies <- reply{line, err}
+	}()
+
+	var line []byte
+	var readErr error
+	select {
+	case r := <-replies:
+		line, readErr = r.line, r.err
+	case <-ctx.Done():
+		w.stop()
+		<-replies
+		return workerResponse{}, fmt.Errorf("exec error: %w", ctx.Err())
+	}
+
+	if len(line) == 0 && readErr != nil {
+		_ = w.stdin.Close()
+		<-w.done
+
+		errText := w.stderr.String()
+		if strings.Contains(errText, errorNotFoundMsg) {
+			return workerResponse{}, ErrNotFound
+		}
+		return workerResponse{}, fmt.Errorf("exec error: worker exited: %v\nstderr: %s", w.exitErr, errText)
+	}
+
+	var resp workerResponse
+	if err := json.Unmarshal(line, &resp); err != nil {
+		w.stop()
+		return workerResponse{}, fmt.Errorf("json decode error: %w", err)
+	}
+	return resp, nil
+}
+
+func workerRunDir(modRoot, absPath, setID string) string {
+	key := absPath
+	if setID != "" {
+		key = setID + "\x00" + absPath
+	}
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(modRoot, barryTmpDir, "workers", fmt.Sprintf("%x", hash[:8]))
+}
+
+type tailBuffer struct {
+	mu    sync.Mutex
+	limit int
+	buf   []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.limit {
+		t.buf = t.buf[len(t.buf)-t.limit:]
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
@@ -0,0 +1,552 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func setupWorkerModule(t *testing.T, module string) string {
+	t.Helper()
+
+	originalMod := findGoModRoot
+	runnerTemplate = defaultRunnerTemplate
+	t.Cleanup(func() {
+		findGoModRoot = originalMod
+		StopWorkers()
+	})
+
+	tmp := t.TempDir()
+	_ = os.WriteFile(filepath.Join(tmp, "go.mod"), []byte("module "+module+"\n"), 0644)
+
+	findGoModRoot = func(startPath string) (string, string, error) {
+		return tmp, module, nil
+	}
+	return tmp
+}
+
+func writeWorkerFile(t *testing.T, path, code string, mtime time.Time) {
+	t.Helper()
+	_ = os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Chtimes(path, mtime, mtime)
+	_ = os.Chtimes(filepath.Dir(path), mtime, mtime)
+}
+
+const workerPidHandler = `package pid
+
+import (
+	"net/http"
+	"os"
+)
+
+func HandleRequest(r *http.Request, p map[string]string) (map[string]interface{}, error) {
+	if p["crash"] == "1" {
+		os.Exit(3)
+	}
+	if p["fail"] == "1" {
+		return nil, http.ErrNoCookie
+	}
+	return map[string]interface{}{"pid": os.Getpid()}, nil
+}
+`
+
+func TestWorker_ReusesProcessAcrossRequests(t *testing.T) {
+	tmp := setupWorkerModule(t, "example.com/workerreuse")
+	goFile := filepath.Join(tmp, "routes", "pid", "index.server.go")
+	writeWorkerFile(t, goFile, workerPidHandler, time.Now())
+
+	first, err := ExecuteServerFileWithSubprocess(goFile, httptest.NewRequest(http.MethodGet, "/pid", nil), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	binPath := workerFor(goFile).binPath
+	built := modTime(binPath)
+
+	second, err := ExecuteServerFileWithSubprocess(goFile, httptest.NewRequest(http.MethodGet, "/pid", nil), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if first["pid"] != second["pid"] {
+		t.Errorf("expected the same worker to serve both requests, got pids %v and %v", first["pid"], second["pid"])
+	}
+	if !modTime(binPath).Equal(built) {
+		t.Error("expected worker binary not to be rebuilt")
+	}
+}
+
+func TestWorker_RebuildsWhenImportChanges(t *testing.T) {
+	tmp := setupWorkerModule(t, "example.com/workerdeps")
+	past := time.Now().Add(-time.Hour)
+
+	libFile := filepath.Join(tmp, "lib", "lib.go")
+	writeWorkerFile(t, libFile, "package lib\n\nfunc Value() string { return \"one\" }\n", past)
+
+	goFile := filepath.Join(tmp, "routes", "deps", "index.server.go")
+	writeWorkerFile(t, goFile, `package deps
+
+import (
+	"net/http"
+
+	"example.com/workerdeps/lib"
+)
+
+func HandleRequest(r *http.Request, _ map[string]string) (map[string]interface{}, error) {
+	return map[string]interface{}{"value": lib.Value()}, nil
+}
+`, past)
+
+	result, err := ExecuteServerFileWithSubprocess(goFile, httptest.NewRequest(http.MethodGet, "/deps", nil), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["value"] != "one" {
+		t.Fatalf("expected one, got %v", result["value"])
+	}
+
+	writeWorkerFile(t, libFile, "package lib\n\nfunc Value() string { return \"two\" }\n", time.Now())
+
+	result, err = ExecuteServerFileWithSubprocess(goFile, httptest.NewRequest(http.MethodGet, "/deps", nil), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["value"] != "two" {
+		t.Errorf("expected worker to rebuild after import changed, got %v", result["value"])
+	}
+}
+
+func TestWorker_RestartsAfterCrash(t *testing.T) {
+	tmp := setupWorkerModule(t, "example.com/workercrash")
+	goFile := filepath.Join(tmp, "routes", "pid", "index.server.go")
+	writeWorkerFile(t, goFile, workerPidHandler, time.Now())
+
+	req := func() *http.Request { return httptest.NewRequest(http.MethodGet, "/pid", nil) }
+
+	first, err := ExecuteServerFileWithSubprocess(goFile, req(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = ExecuteServerFileWithSubprocess(goFile, req(), map[string]string{"fail": "1"})
+	if err == nil || err.Error() != http.ErrNoCookie.Error() {
+		t.Fatalf("expected handler error, got %v", err)
+	}
+
+	_, err = ExecuteServerFileWithSubprocess(goFile, req(), map[string]string{"crash": "1"})
+	if err == nil || !strings.Contains(err.Error(), "worker exited") {
+		t.Fatalf("expected crash error, got %v", err)
+	}
+
+	second, err := ExecuteServerFileWithSubprocess(goFile, req(), nil)
+	if err != nil {
+		t.Fatalf("expected worker to restart, got %v", err)
+	}
+	if first["pid"] == second["pid"] {
+		t.Error("expected a new worker process after the crash")
+	}
+}
+
+func TestTailBuffer_KeepsLastBytes(t *testing.T) {
+	buf := &tailBuffer{limit: 5}
+	_, _ = buf.Write([]byte("abc"))
+	_, _ = buf.Write([]byte("defg"))
+
+	if got := buf.String(); got != "cdefg" {
+		t.Errorf("expected cdefg, got %q", got)
+	}
+}
+
+func TestWorker_RebuildsRequestFaithfully(t *testing.T) {
+	tmp := setupWorkerModule(t, "example.com/workerrequest")
+	goFile := filepath.Join(tmp, "api", "echo", "post.go")
+	writeWorkerFile(t, goFile, `package echo
+
+import (
+	"io"
+	"net/http"
+)
+
+func HandlePost(r *http.Request, p map[string]string) (map[string]interface{}, error) {
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		return nil, err
+	}
+	file, _, err := r.FormFile("upload")
+	if err != nil {
+		return nil, err
+	}
+	data, _ := io.ReadAll(file)
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"param":      p["slug"],
+		"upload":     data,
+		"field":      r.FormValue("note"),
+		"cookie":     cookie.Value,
+		"requestURI": r.RequestURI,
+		"proto":      r.Proto,
+		"tls":        r.TLS != nil,
+		"length":     r.ContentLength,
+	}, nil
+}
+`, time.Now())
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("upload", "blob.bin")
+	_, _ = part.Write([]byte{0x00, 0xff, 0x10, '"', '\\'})
+	_ = mw.WriteField("note", `say "hi" \ bye`)
+	_ = mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/api/echo?x=1", bytes.NewReader(body.Bytes()))
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc123"})
+
+	params := map[string]string{"slug": "quote\" back\\slash `tick`"}
+	result, err := ExecuteServerFileWithSubprocess(goFile, withHandlerName(req, "HandlePost"), params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"param":      params["slug"],
+		"upload":     base64.StdEncoding.EncodeToString([]byte{0x00, 0xff, 0x10, '"', '\\'}),
+		"field":      `say "hi" \ bye`,
+		"cookie":     "abc123",
+		"requestURI": "https://example.com/api/echo?x=1",
+		"proto":      "HTTP/1.1",
+		"tls":        true,
+		"length":     int64(body.Len()),
+	}
+	for key, want := range expected {
+		if result[key] != want {
+			t.Errorf("%s: expected %v, got %v", key, want, result[key])
+		}
+	}
+}
+
+func TestWorker_ParsesFormBeforeDispatch(t *testing.T) {
+	tmp := setupWorkerModule(t, "example.com/workerform")
+	goFile := filepath.Join(tmp, "api", "form", "post.go")
+	writeWorkerFile(t, goFile, `package form
+
+import (
+	"io"
+	"net/http"
+)
+
+func HandlePost(r *http.Request, p map[string]string) (map[string]interface{}, error) {
+	body, _ := io.ReadAll(r.Body)
+	return map[string]interface{}{
+		"post":  r.PostForm.Get("name"),
+		"query": r.Form.Get("q"),
+		"body":  string(body),
+	}, nil
+}
+`, time.Now())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/form?q=hats", strings.NewReader("name=Ann"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	result, err := ExecuteServerFileWithSubprocess(goFile, withHandlerName(req, "HandlePost"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["post"] != "Ann" || result["query"] != "hats" || result["body"] != "name=Ann" {
+		t.Errorf("expected parsed form and readable body, got %v", result)
+	}
+}
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWorker_WritesStderrToLogger(t *testing.T) {
+	tmp := setupWorkerModule(t, "example.com/workerlog")
+	goFile := filepath.Join(tmp, "routes", "log", "index.server.go")
+	writeWorkerFile(t, goFile, `package log
+
+import (
+	"log"
+	"net/http"
+)
+
+func HandleRequest(r *http.Request, p map[string]string) (map[string]interface{}, error) {
+	log.Println("hello from the worker")
+	return map[string]interface{}{}, nil
+}
+`, time.Now())
+
+	var logs lockedBuffer
+	set := newWorkerSet(&logs)
+	defer set.stop()
+
+	req := httptest.NewRequest(http.MethodGet, "/log", nil)
+	req = req.WithContext(withWorkers(req.Context(), set))
+	if _, err := ExecuteServerFileWithSubprocess(goFile, req, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(logs.String(), "hello from the worker") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(logs.String(), "hello from the worker") {
+		t.Errorf("expected worker stderr on the logger, got %q", logs.String())
+	}
+}
+
+func TestWorker_KillsHungHandlerOnTimeout(t *testing.T) {
+	tmp := setupWorkerModule(t, "example.com/workerhang")
+	goFile := filepath.Join(tmp, "routes", "hang", "index.server.go")
+	writeWorkerFile(t, goFile, `package hang
+
+import (
+	"net/http"
+	"os"
+	"time"
+)
+
+func HandleRequest(r *http.Request, p map[string]string) (map[string]interface{}, error) {
+	if p["hang"] == "1" {
+		time.Sleep(time.Hour)
+	}
+	return map[string]interface{}{"pid": os.Getpid()}, nil
+}
+`, time.Now())
+
+	first, err := ExecuteServerFileWithSubprocess(goFile, httptest.NewRequest(http.MethodGet, "/hang", nil), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/hang", nil).WithContext(ctx)
+
+	_, err = ExecuteServerFileWithSubprocess(goFile, req, map[string]string{"hang": "1"})
+	if !IsTimeoutError(err) {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+
+	second, err := ExecuteServerFileWithSubprocess(goFile, httptest.NewRequest(http.MethodGet, "/hang", nil), nil)
+	if err != nil {
+		t.Fatalf("expected worker to restart after timeout, got %v", err)
+	}
+	if first["pid"] == second["pid"] {
+		t.Error("expected the hung worker to be killed")
+	}
+}
+
+func TestWorker_ForwardsStatusErrors(t *testing.T) {
+	tmp := setupWorkerModule(t, "example.com/workerstatus")
+	goFile := filepath.Join(tmp, "routes", "status", "index.server.go")
+	writeWorkerFile(t, goFile, `package status
+
+import "net/http"
+
+type redirect struct{ to string }
+
+func (r redirect) Error() string            { return "redirect" }
+func (r redirect) StatusCode() int          { return http.StatusSeeOther }
+func (r redirect) RedirectLocation() string { return r.to }
+
+func HandleRequest(r *http.Request, p map[string]string) (map[string]interface{}, error) {
+	return nil, redirect{to: "/login"}
+}
+`, time.Now())
+
+	_, err := ExecuteServerFileWithSubprocess(goFile, httptest.NewRequest(http.MethodGet, "/status", nil), nil)
+
+	re, ok := asResponseError(err)
+	if !ok || re.Status != http.StatusSeeOther || re.Location != "/login" {
+		t.Errorf("expected redirect response error, got %v", err)
+	}
+}
+
+func TestWorker_ForwardsResponseMeta(t *testing.T) {
+	tmp := setupWorkerModule(t, "example.com/workermeta")
+	goFile := filepath.Join(tmp, "routes", "meta", "index.server.go")
+	writeWorkerFile(t, goFile, `package meta
+
+import "net/http"
+
+type ResponseMeta struct {
+	Status  int
+	Headers map[string]string
+}
+
+type reply struct{}
+
+func (reply) Map() map[string]interface{} { return map[string]interface{}{"_status": "data"} }
+func (reply) ResponseMeta() ResponseMeta {
+	return ResponseMeta{Status: http.StatusAccepted, Headers: map[string]string{"X-Meta": "1"}}
+}
+
+func HandleRequest(r *http.Request, p map[string]string) (interface{}, error) {
+	return reply{}, nil
+}
+`, time.Now())
+
+	req, slot := withResponseMeta(httptest.NewRequest(http.MethodGet, "/meta", nil))
+	result, err := ExecuteServerFileWithSubprocess(goFile, req, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result) != 1 || result["_status"] != "data" {
+		t.Errorf("expected data to pass through untouched, got %#v", result)
+	}
+	if meta := slot.get(); meta.Status != http.StatusAccepted || meta.Headers["X-Meta"] != "1" {
+		t.Errorf("expected meta to be forwarded, got %+v", meta)
+	}
+}
+
+func TestWorker_TypedResultKeepsIntegers(t *testing.T) {
+	tmp := setupWorkerModule(t, "example.com/workertyped")
+	goFile := filepath.Join(tmp, "routes", "typed", "index.server.go")
+	writeWorkerFile(t, goFile, `package typed
+
+import "net/http"
+
+type Meta struct {
+	Views int
+}
+
+type PageData struct {
+	Meta
+	Title  string
+	Price  int     `+"`json:\"price\"`"+`
+	Rating float64
+	Tags   []int
+	secret string
+}
+
+func HandleRequest(r *http.Request, p map[string]string) (*PageData, error) {
+	return &PageData{Meta: Meta{Views: 7}, Title: "Hat", Price: 3000000, Rating: 4.5, Tags: []int{1, 2}}, nil
+}
+`, time.Now())
+
+	result, err := ExecuteServerFileWithSubprocess(goFile, httptest.NewRequest(http.MethodGet, "/typed", nil), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result["price"] != int64(3000000) {
+		t.Errorf("expected price to stay an integer, got %#v", result["price"])
+	}
+	if result["Views"] != int64(7) || result["Title"] != "Hat" || result["Rating"] != 4.5 {
+		t.Errorf("unexpected result %#v", result)
+	}
+	if tags, _ := result["Tags"].([]interface{}); len(tags) != 2 || tags[0] != int64(1) {
+		t.Errorf("expected integer tags, got %#v", result["Tags"])
+	}
+	if _, ok := result["secret"]; ok {
+		t.Errorf("expected unexported field to be dropped, got %#v", result)
+	}
+}
+
+func TestWorker_RunsComponentHandlers(t *testing.T) {
+	tmp := setupWorkerModule(t, "example.com/workercomponent")
+	goFile := filepath.Join(tmp, "components", "widgets", "latest.server.go")
+	writeWorkerFile(t, goFile, `package widgets
+
+import "net/http"
+
+func HandleLatestPosts(r *http.Request, props map[string]string) (map[string]interface{}, error) {
+	return map[string]interface{}{"limit": props["limit"]}, nil
+}
+`, time.Now())
+	writeWorkerFile(t, filepath.Join(tmp, "components", "widgets", "card.server.go"), `package widgets
+
+import "net/http"
+
+func HandleCard(r *http.Request, props map[string]string) (map[string]interface{}, error) {
+	return map[string]interface{}{"card": true}, nil
+}
+`, time.Now())
+
+	req := withHandlerName(httptest.NewRequest(http.MethodGet, "/", nil), "HandleLatestPosts")
+	result, err := ExecuteServerFileWithSubprocess(goFile, req, map[string]string{"limit": "5"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["limit"] != "5" {
+		t.Errorf("unexpected result %#v", result)
+	}
+}
+
+func TestWorker_ServesConcurrentRequestsInParallel(t *testing.T) {
+	tmp := setupWorkerModule(t, "example.com/workerparallel")
+	goFile := filepath.Join(tmp, "routes", "sleep", "index.server.go")
+	writeWorkerFile(t, goFile, `package sleep
+
+import (
+	"net/http"
+	"os"
+	"time"
+)
+
+func HandleRequest(r *http.Request, p map[string]string) (map[string]interface{}, error) {
+	if p["sleep"] == "1" {
+		time.Sleep(300 * time.Millisecond)
+	}
+	return map[string]interface{}{"pid": os.Getpid()}, nil
+}
+`, time.Now())
+
+	if _, err := ExecuteServerFileWithSubprocess(goFile, httptest.NewRequest(http.MethodGet, "/sleep", nil), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ExecuteServerFileWithSubprocess(goFile, httptest.NewRequest(http.MethodGet, "/sleep", nil), map[string]string{"sleep": "1"})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 800*time.Millisecond {
+		t.Errorf("expected requests to run in parallel, took %s", elapsed)
+	}
+}
@@ -2,8 +2,11 @@ package barry
 
 import (
 	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -16,11 +19,37 @@ type RuntimeConfig struct {
 	Port        int
 }
 
+type Option func(*serverOptions)
+
+type serverOptions struct {
+	services map[string]interface{}
+	fsys     fs.FS
+}
+
+func WithService(name string, svc interface{}) Option {
+	return func(o *serverOptions) {
+		if o.services == nil {
+			o.services = map[string]interface{}{}
+		}
+		o.services[name] = svc
+	}
+}
+
+func WithFS(fsys fs.FS) Option {
+	return func(o *serverOptions) {
+		o.fsys = fsys
+	}
+}
+
+func Service(r *http.Request, name string) (interface{}, bool) {
+	return core.Service(r, name)
+}
+
 var ListenAndServe = http.ListenAndServe
 var Exit = os.Exit
 
-var Start = func(cfg RuntimeConfig) {
-	addr, handler := BuildServer(cfg)
+var Start = func(cfg RuntimeConfig, opts ...Option) {
+	addr, handler := BuildServer(cfg, opts...)
 	fmt.Printf("✅ Barry running at http://localhost%s\n", addr)
 
 	if err := ListenAndServe(addr, handler); err != nil {
@@ -29,55 +58,41 @@ var Start = func(cfg RuntimeConfig) {
 	}
 }
 
-func BuildServer(cfg RuntimeConfig) (string, http.Handler) {
+func BuildServer(cfg RuntimeConfig, opts ...Option) (string, http.Handler) {
 	fmt.Println("🚀 Starting Barry in", cfg.Env, "mode...")
 
-	config := core.LoadConfig("barry.config.yml")
-	config.CacheEnabled = cfg.EnableCache
-
-	mux := http.NewServeMux()
-	publicDir := "public"
-	cacheStaticDir := filepath.Join(config.OutputDir, "static")
-
-	if cfg.Env == "dev" {
-		setupDevStaticRoutes(mux, publicDir)
-	} else {
-		mux.HandleFunc("/static/", makeStaticHandler(publicDir, cacheStaticDir))
-		mux.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
-			serveFileWithHeaders(w, r, filepath.Join(publicDir, "favicon.ico"), "public, max-age=31536000, immutable")
-		})
-		mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
-			serveFileWithHeaders(w, r, filepath.Join(publicDir, "robots.txt"), "public, max-age=31536000, immutable")
-		})
+	options := serverOptions{}
+	for _, opt := range opts {
+		opt(&options)
 	}
 
-	router := core.NewRouter(*config, core.RuntimeContext{
-		Env:         cfg.Env,
-		EnableWatch: cfg.Env == "dev",
-		OnReload:    nil,
-	})
-
-	if cfg.Env == "dev" {
-		reloader := core.NewLiveReloader()
-		router = core.NewRouter(*config, core.RuntimeContext{
-			Env:         cfg.Env,
-			EnableWatch: true,
-			OnReload:    reloader.BroadcastReload,
-		})
-		mux.HandleFunc("/__barry_reload", reloader.Handler)
+	config := core.LoadConfig("barry.config.yml")
+	if options.fsys != nil {
+		config = core.LoadConfigFS(options.fsys, "barry.config.yml")
 	}
+	config.CacheEnabled = cfg.EnableCache
 
-	mux.Handle("/", router)
+	server, err := newServer(Options{
+		FS:       options.fsys,
+		Config:   config,
+		Env:      cfg.Env,
+		Services: options.services,
+	}, "barry.config.yml")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		Exit(1)
+		return "", http.NotFoundHandler()
+	}
 
 	addr := fmt.Sprintf(":%d", cfg.Port)
-	return addr, mux
+	return addr, server
 }
 
 func acceptsGzip(r *http.Request) bool {
 	return strings.Contains(r.Header.Get("Accept-Encoding"), "gzip")
 }
 
-func makeStaticHandler(publicDir, cacheStaticDir string) http.HandlerFunc {
+func makeStaticHandler(fsys fs.FS, publicDir, cacheStaticDir string, logger io.Writer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		uri := r.URL.Path
 		trimmed := strings.TrimPrefix(uri, "/static/")
@@ -107,12 +122,12 @@ func makeStaticHandler(publicDir, cacheStaticDir string) http.HandlerFunc {
 		}
 
 		publicFile := filepath.Join(publicDir, trimmed)
-		if _, err := os.Stat(publicFile); err == nil {
-			serveFileWithHeaders(w, r, publicFile, "public, max-age=31536000, immutable")
+		if core.SiteFileExists(fsys, publicFile) {
+			serveSiteFileWithHeaders(w, r, fsys, publicFile, "public, max-age=31536000, immutable")
 			return
 		}
 
-		fmt.Printf("🛑 Static asset not found: %s\n", r.URL.Path)
+		fmt.Fprintf(logger, "🛑 Static asset not found: %s\n", r.URL.Path)
 		http.NotFound(w, r)
 	}
 }
@@ -146,20 +161,26 @@ func serveFileWithHeaders(w http.ResponseWriter, r *http.Request, filePath, cach
 	http.ServeFile(w, r, filePath)
 }
 
-func setupDevStaticRoutes(mux *http.ServeMux, publicDir string) {
+func serveSiteFileWithHeaders(w http.ResponseWriter, r *http.Request, fsys fs.FS, filePath, cacheControl string) {
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("Content-Type", detectMimeType(filePath))
+	core.ServeSiteFile(w, r, fsys, filePath)
+}
+
+func setupDevStaticRoutes(mux *http.ServeMux, fsys fs.FS, publicDir string) {
 	staticHandler := http.StripPrefix("/static/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Cache-Control", "no-store")
-		http.FileServer(http.Dir(publicDir)).ServeHTTP(w, r)
+		core.ServeSiteFile(w, r, fsys, filepath.Join(publicDir, filepath.FromSlash(path.Clean("/"+r.URL.Path))))
 	}))
 	mux.Handle("/static/", staticHandler)
 
 	mux.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Cache-Control", "no-store")
-		http.ServeFile(w, r, filepath.Join(publicDir, "favicon.ico"))
+		core.ServeSiteFile(w, r, fsys, filepath.Join(publicDir, "favicon.ico"))
 	})
 
 	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Cache-Control", "no-store")
-		http.ServeFile(w, r, filepath.Join(publicDir, "robots.txt"))
+		core.ServeSiteFile(w, r, fsys, filepath.Join(publicDir, "robots.txt"))
 	})
 }
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/go-barry/barry/core"
 )
@@ -89,7 +90,7 @@ func TestMakeStaticHandlerReturns404ForMissingFile(t *testing.T) {
 	publicDir := t.TempDir()
 	cacheDir := t.TempDir()
 
-	handler := makeStaticHandler(publicDir, cacheDir)
+	handler := makeStaticHandler(nil, publicDir, cacheDir, io.Discard)
 
 	req := httptest.NewRequest(http.MethodGet, "/static/missing.txt", nil)
 	rec := httptest.NewRecorder()
@@ -111,7 +112,7 @@ func TestMakeStaticHandlerServesPublicFile(t *testing.T) {
 	expected := "Hello from public!"
 	_ = os.WriteFile(filePath, []byte(expected), 0644)
 
-	handler := makeStaticHandler(publicDir, cacheDir)
+	handler := makeStaticHandler(nil, publicDir, cacheDir, io.Discard)
 
 	req := httptest.NewRequest(http.MethodGet, "/static/"+testFile, nil)
 	rec := httptest.NewRecorder()
@@ -134,7 +135,7 @@ func TestMakeStaticHandlerRejectsTraversal(t *testing.T) {
 	publicDir := t.TempDir()
 	cacheDir := t.TempDir()
 
-	handler := makeStaticHandler(publicDir, cacheDir)
+	handler := makeStaticHandler(nil, publicDir, cacheDir, io.Discard)
 
 	req := httptest.NewRequest(http.MethodGet, "/static/../secrets.txt", nil)
 	rec := httptest.NewRecorder()
@@ -156,7 +157,7 @@ func TestMakeStaticHandlerServesGzipFromCache(t *testing.T) {
 
 	_ = os.WriteFile(gzipFile, []byte("gzipped content"), 0644)
 
-	handler := makeStaticHandler(publicDir, cacheDir)
+	handler := makeStaticHandler(nil, publicDir, cacheDir, io.Discard)
 
 	req := httptest.NewRequest(http.MethodGet, "/static/"+fileName, nil)
 	req.Header.Set("Accept-Encoding", "gzip")
@@ -185,7 +186,7 @@ func TestMakeStaticHandlerServesNonGzipCacheFile(t *testing.T) {
 	cachedFile := filepath.Join(cacheDir, fileName)
 	_ = os.WriteFile(cachedFile, []byte("cached css"), 0644)
 
-	handler := makeStaticHandler(publicDir, cacheDir)
+	handler := makeStaticHandler(nil, publicDir, cacheDir, io.Discard)
 
 	req := httptest.NewRequest(http.MethodGet, "/static/"+fileName, nil)
 	req.Header.Set("Accept-Encoding", "gzip")
@@ -214,7 +215,7 @@ func TestSetupDevStaticRoutesFaviconAndRobots(t *testing.T) {
 	_ = os.WriteFile(robotsPath, []byte("robots"), 0644)
 
 	mux := http.NewServeMux()
-	setupDevStaticRoutes(mux, publicDir)
+	setupDevStaticRoutes(mux, nil, publicDir)
 
 	tests := []struct {
 		path     string
@@ -250,7 +251,7 @@ func TestMakeStaticHandlerStripsQueryParams(t *testing.T) {
 	fileName := "main.js"
 	_ = os.WriteFile(filepath.Join(publicDir, fileName), []byte("main js"), 0644)
 
-	handler := makeStaticHandler(publicDir, cacheDir)
+	handler := makeStaticHandler(nil, publicDir, cacheDir, io.Discard)
 
 	req := httptest.NewRequest(http.MethodGet, "/static/main.js?v=1234", nil)
 	rec := httptest.NewRecorder()
@@ -367,7 +368,7 @@ func TestDevStaticRoutes_FileServerAddsNoStore(t *testing.T) {
 	_ = os.WriteFile(filepath.Join(publicDir, "test.js"), []byte("content"), 0644)
 
 	mux := http.NewServeMux()
-	setupDevStaticRoutes(mux, publicDir)
+	setupDevStaticRoutes(mux, nil, publicDir)
 
 	req := httptest.NewRequest(http.MethodGet, "/static/test.js", nil)
 	rec := httptest.NewRecorder()
@@ -428,6 +429,42 @@ func TestBuildServerInProd(t *testing.T) {
 	}
 }
 
+func TestBuildServer_WithFSServesEmbeddedPublicFiles(t *testing.T) {
+	originalLoadConfig := core.LoadConfig
+	originalNewRouter := core.NewRouter
+	t.Cleanup(func() {
+		core.LoadConfig = originalLoadConfig
+		core.NewRouter = originalNewRouter
+	})
+
+	core.LoadConfig = func(path string) *core.Config {
+		return &core.Config{OutputDir: t.TempDir()}
+	}
+	core.NewRouter = func(c core.Config, ctx core.RuntimeContext) http.Handler {
+		return http.NotFoundHandler()
+	}
+
+	site := fstest.MapFS{
+		"public/css/app.css": {Data: []byte("body{}")},
+		"public/robots.txt":  {Data: []byte("embedded robots")},
+	}
+
+	for _, env := range []string{"prod", "dev"} {
+		_, handler := BuildServer(RuntimeConfig{Env: env, Port: 1234}, WithFS(site))
+
+		for path, want := range map[string]string{
+			"/static/css/app.css": "body{}",
+			"/robots.txt":         "embedded robots",
+		} {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			if rec.Body.String() != want {
+				t.Errorf("%s %s: expected %q, got %d %q", env, path, want, rec.Code, rec.Body.String())
+			}
+		}
+	}
+}
+
 func TestStart_ExitsOnServerFailure(t *testing.T) {
 	var exited bool
 	var exitCode int
@@ -475,3 +512,126 @@ func TestStart_ExitsOnServerFailure(t *testing.T) {
 		t.Errorf("unexpected stderr output: %q", stderr)
 	}
 }
+
+func TestBuildServerAppliesPublicRules(t *testing.T) {
+	publicDir := "public"
+	_ = os.MkdirAll(publicDir, 0755)
+	_ = os.WriteFile(filepath.Join(publicDir, "robots.txt"), []byte("robots"), 0644)
+	_ = os.WriteFile(filepath.Join(publicDir, "_headers"), []byte("/*\n  Cache-Control: no-cache\n"), 0644)
+	_ = os.WriteFile(filepath.Join(publicDir, "_redirects"), []byte("/legacy /\n"), 0644)
+
+	t.Cleanup(func() {
+		_ = os.RemoveAll(publicDir)
+	})
+
+	originalLoadConfig := core.LoadConfig
+	originalNewRouter := core.NewRouter
+	defer func() {
+		core.LoadConfig = originalLoadConfig
+		core.NewRouter = originalNewRouter
+	}()
+
+	core.LoadConfig = func(path string) *core.Config {
+		return &core.Config{OutputDir: t.TempDir()}
+	}
+	core.NewRouter = func(c core.Config, ctx core.RuntimeContext) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "router")
+		})
+	}
+
+	_, handler := BuildServer(RuntimeConfig{Env: "prod", Port: 1234})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+	if cc := rec.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("expected Cache-Control from _headers, got %q", cc)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/legacy", nil))
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/" {
+		t.Errorf("expected 301 to /, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestBuildServerMountsBasePath(t *testing.T) {
+	originalLoadConfig := core.LoadConfig
+	originalNewRouter := core.NewRouter
+	originalNewLiveReloader := core.NewLiveReloader
+	defer func() {
+		core.LoadConfig = originalLoadConfig
+		core.NewRouter = originalNewRouter
+		core.NewLiveReloader = originalNewLiveReloader
+	}()
+
+	core.LoadConfig = func(path string) *core.Config {
+		return &core.Config{OutputDir: t.TempDir(), BasePath: "/docs"}
+	}
+	core.NewRouter = func(c core.Config, ctx core.RuntimeContext) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "router "+r.URL.Path)
+		})
+	}
+	core.NewLiveReloader = func() core.LiveReloaderInterface {
+		return &mockReloader{}
+	}
+
+	_, handler := BuildServer(RuntimeConfig{Env: "dev", Port: 1234})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/__barry_reload", nil))
+	if rec.Body.String() != "reload ok" {
+		t.Errorf("expected reload socket under base path, got %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/about", nil))
+	if rec.Body.String() != "router /about" {
+		t.Errorf("expected router to see stripped path, got %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/about", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 outside base path, got %d", rec.Code)
+	}
+}
+
+func TestBuildServerPassesServicesToRouter(t *testing.T) {
+	originalLoadConfig := core.LoadConfig
+	originalNewRouter := core.NewRouter
+	defer func() {
+		core.LoadConfig = originalLoadConfig
+		core.NewRouter = originalNewRouter
+	}()
+
+	core.LoadConfig = func(path string) *core.Config {
+		return &core.Config{OutputDir: t.TempDir()}
+	}
+
+	var got map[string]interface{}
+	core.NewRouter = func(c core.Config, ctx core.RuntimeContext) http.Handler {
+		got = ctx.Services
+		return http.NotFoundHandler()
+	}
+
+	pool := &struct{ name string }{"pool"}
+	BuildServer(RuntimeConfig{Env: "prod", Port: 1234}, WithService("db", pool), WithService("region", "eu"))
+
+	if got["db"] != pool || got["region"] != "eu" {
+		t.Errorf("expected services to reach the router, got %v", got)
+	}
+}
+
+func TestService_ReadsFromRequestContext(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, ok := Service(req, "db"); ok {
+		t.Error("expected no service on a plain request")
+	}
+
+	req = req.WithContext(core.WithServices(req.Context(), map[string]interface{}{"db": "pool"}))
+	if svc, ok := Service(req, "db"); !ok || svc != "pool" {
+		t.Errorf("expected db service, got %v", svc)
+	}
+}